@@ -1,66 +1,126 @@
 package cmd
 
 import (
-	"context"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
-
+	"github.com/smiletrl/tss-lib-starter/pkg/config"
 	"github.com/smiletrl/tss-lib-starter/pkg/constants"
-	pbClient "github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
-	pbServer "github.com/smiletrl/tss-lib-starter/pkg/grpc/server"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/creds"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
 	"github.com/smiletrl/tss-lib-starter/pkg/party"
 )
 
-func Cmd(signMessage string) {
-	err := godotenv.Load()
+// loadConfig loads the roster, threshold and addresses of all parties from `CONFIG_FILE`, or the test deployment of
+// four local parties by default.
+func loadConfig() *config.Config {
+	path := os.Getenv(constants.EnvConfigFile)
+	if path == "" {
+		log.Printf("config file is not set, use the test deployment of four local parties")
+		return config.Default()
+	}
+	cfg, err := config.Load(path)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		panic("error loading config:" + err.Error())
 	}
+	return cfg
+}
 
-	envPartyID := os.Getenv(constants.EnvPartyID)
-	if envPartyID == "" {
-		panic("env party id is not set yet")
+// loadTLSConfig returns the tls files of this node, tls is disabled if no file is set.
+func loadTLSConfig() creds.Config {
+	return creds.Config{
+		CertFile: os.Getenv(constants.EnvTLSCertFile),
+		KeyFile:  os.Getenv(constants.EnvTLSKeyFile),
+		CAFile:   os.Getenv(constants.EnvTLSCAFile),
 	}
+}
 
-	// init pb clients
-	client, err := pbClient.NewClient(constants.TestGrpcHost)
+// loadIdentity loads the identity keys signing all messages between parties, and encrypting point to point messages.
+// Messages are neither signed nor encrypted if no identity is set.
+func loadIdentity(partyID string) (*identity.Identity, identity.Registry) {
+	identityFile, registryFile := os.Getenv(constants.EnvIdentityFile), os.Getenv(constants.EnvIdentityRegistryFile)
+	if identityFile == "" && registryFile == "" {
+		log.Printf("identity is not set, messages between parties are neither signed nor encrypted")
+		return nil, nil
+	}
+	id, err := identity.LoadIdentity(identityFile)
+	if err != nil {
+		panic("error loading identity:" + err.Error())
+	}
+	if id.ID != partyID {
+		panic("unexpected identity of party:" + id.ID)
+	}
+	registry, err := identity.LoadRegistry(registryFile)
 	if err != nil {
-		panic("error initializing pb client:" + err.Error())
+		panic("error loading identity registry:" + err.Error())
 	}
+	return id, registry
+}
 
-	// init local party
-	p := party.NewParty(client)
+// newKeyStore returns the key store, key shares are saved within `keys` dir by default.
+func newKeyStore() party.KeyStore {
+	keyStoreDir := os.Getenv(constants.EnvKeyStoreDir)
+	if keyStoreDir == "" {
+		keyStoreDir = "keys"
+	}
+	keyStore, err := party.NewFileKeyStore(keyStoreDir)
+	if err != nil {
+		panic("error initializing key store:" + err.Error())
+	}
+	return keyStore
+}
 
-	// init pb server
-	go func(p party.Party) {
-		log.Println("grpc server starts")
-		if err := pbServer.RegisterServer(envPartyID, p); err != nil {
-			panic("error register server:" + err.Error())
+// newPreParamsPool returns the ecdsa keygen pre-params pool, saved within `preparams` dir by default. Pool size 0
+// means one pre-params is generated once and reused.
+func newPreParamsPool() *party.PreParamsPool {
+	preParamsDir := os.Getenv(constants.EnvPreParamsDir)
+	if preParamsDir == "" {
+		preParamsDir = "preparams"
+	}
+	preParamsPoolSize := 0
+	if size := os.Getenv(constants.EnvPreParamsPoolSize); size != "" {
+		var err error
+		if preParamsPoolSize, err = strconv.Atoi(size); err != nil {
+			panic("error parsing pre-params pool size:" + err.Error())
 		}
-	}(p)
-
-	p.GatherSharedParties()
-	p.SetLocalID(envPartyID)
-
-	log.Printf("prepare keygen")
-
-	p.PrepareKeygen()
-
-	// start keygen process
-	go p.Keygen()
-	log.Printf("wait for keygen")
+	}
+	preParamsPool, err := party.NewPreParamsPool(preParamsDir, preParamsPoolSize)
+	if err != nil {
+		panic("error initializing pre-params pool:" + err.Error())
+	}
+	return preParamsPool
+}
 
-	// wait for keygen process finished
-	p.WaitForKeygen()
-	log.Printf("keygen process finished")
+// ceremonyTimeout returns the deadline of each keygen/signing ceremony, `CEREMONY_TIMEOUT` i.e, `2m`, or the given
+// default.
+func ceremonyTimeout(def time.Duration) time.Duration {
+	t := os.Getenv(constants.EnvCeremonyTimeout)
+	if t == "" {
+		return def
+	}
+	timeout, err := time.ParseDuration(t)
+	if err != nil {
+		panic("error parsing ceremony timeout:" + err.Error())
+	}
+	return timeout
+}
 
-	if _, ok := constants.SelectedParties[envPartyID]; ok {
-		go p.Sign(context.Background(), []byte(signMessage))
+// keyAlgorithm returns the algorithm of new keys, ecdsa by default.
+func keyAlgorithm() constants.Algorithm {
+	algorithm := constants.Algorithm(os.Getenv(constants.EnvKeyAlgorithm))
+	if algorithm == "" {
+		algorithm = constants.AlgorithmECDSA
 	}
+	return algorithm
+}
 
-	// hang the app
-	end := make(chan string)
-	<-end
+// signers returns the default signers, `SIGNERS` or all parties by default.
+func signers() []string {
+	if s := os.Getenv(constants.EnvSigners); s != "" {
+		return strings.Split(s, ",")
+	}
+	return constants.TestSigners
 }
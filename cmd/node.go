@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/config"
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/discovery"
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	pbClient "github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/creds"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/relay"
+	pbServer "github.com/smiletrl/tss-lib-starter/pkg/grpc/server"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+// node is the local party, connected to the other parties directly or through the relay.
+type node struct {
+	cfg      *config.Config
+	id       string
+	party    party.Party
+	keyStore party.KeyStore
+
+	// reports the connectivity to the other parties
+	peers pbClient.StatusReporter
+}
+
+// startNode starts the local party, and serves messages from the other parties in the background.
+func startNode(cfg *config.Config, partyID string) *node {
+	// init mutual tls between parties, each node holds its own certificate. TLS is disabled if no file is set.
+	tlsConfig := loadTLSConfig()
+	if !tlsConfig.Enabled() {
+		log.Printf("tls is disabled, messages between parties are sent in plain text")
+	}
+	roster := cfg.PartyIDs()
+	serverCreds, err := creds.ServerCredentials(tlsConfig, roster)
+	if err != nil {
+		panic("error initializing server tls:" + err.Error())
+	}
+	clientCreds, err := creds.ClientCredentials(tlsConfig)
+	if err != nil {
+		panic("error initializing client tls:" + err.Error())
+	}
+
+	id, registry := loadIdentity(partyID)
+
+	// init pb clients. With relay, all messages go through the relay instead of the parties' own grpc servers.
+	relayAddr := os.Getenv(constants.EnvRelayAddr)
+	var (
+		client      pbClient.Client
+		relayClient pb.RelayClient
+	)
+	if relayAddr != "" {
+		// the relay hands the frames over to the party of the client certificate
+		if !tlsConfig.Enabled() {
+			panic("tls is required with relay")
+		}
+		if relayClient, err = relay.Dial(relayAddr, clientCreds); err != nil {
+			panic("error dialing relay:" + err.Error())
+		}
+		client, err = pbClient.NewRelayClient(relayClient, roster, id, registry)
+	} else {
+		client, err = pbClient.NewClient(cfg, clientCreds, id, registry)
+	}
+	if err != nil {
+		panic("error initializing pb client:" + err.Error())
+	}
+
+	keyStore := newKeyStore()
+	preParamsPool := newPreParamsPool()
+	preParamsPool.Start(context.Background())
+	timeout := ceremonyTimeout(constants.DefaultCeremonyTimeout)
+
+	// init local party
+	p := party.NewParty(cfg, client, keyStore, preParamsPool, timeout)
+	p.SetLocalID(partyID)
+
+	// parties reached directly agree on the roster by handshakes, the relay only forwards messages, so the config
+	// pins the roster then. Handshakes and the roster are signed by the identity keys, the config pins the roster
+	// without identity.
+	var disc *discovery.Discovery
+	if relayAddr == "" && id == nil {
+		log.Printf("handshake is disabled without identity, the config pins the roster")
+	} else if relayAddr == "" {
+		rosterFile := os.Getenv(constants.EnvRosterFile)
+		if rosterFile == "" {
+			rosterFile = "roster.json"
+		}
+		if disc, err = discovery.New(cfg, partyID, id, registry, client.(pbClient.Handshaker), rosterFile); err != nil {
+			panic("error initializing discovery:" + err.Error())
+		}
+	}
+
+	// init pb server, or receive messages from relay
+	go func(p party.Party) {
+		if relayAddr != "" {
+			log.Printf("receive messages from relay: %s", relayAddr)
+			if err := pbServer.ServeRelay(context.Background(), partyID, p, relayClient, id, registry); err != nil {
+				panic("error serving relay:" + err.Error())
+			}
+			return
+		}
+		log.Println("grpc server starts")
+		if err := pbServer.RegisterServer(cfg, partyID, p, serverCreds, id, registry, disc); err != nil {
+			panic("error register server:" + err.Error())
+		}
+	}(p)
+
+	// agree on the roster in the background, so the node serves while some parties are still down. Keygen and
+	// resharing wait until the roster is agreed.
+	if disc != nil {
+		go func() {
+			parties, err := disc.Run(context.Background())
+			if err != nil {
+				log.Printf("error agreeing on roster: %v", err)
+				return
+			}
+			if err := p.GatherSharedParties(parties); err != nil {
+				log.Printf("error gathering shared parties: %v", err)
+			}
+		}()
+	} else if err := p.GatherSharedParties(cfg.Parties); err != nil {
+		panic("error gathering shared parties:" + err.Error())
+	}
+
+	return &node{
+		cfg:      cfg,
+		id:       partyID,
+		party:    p,
+		keyStore: keyStore,
+		peers:    client.(pbClient.StatusReporter),
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/ceremony"
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	pbClient "github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
+	pbServer "github.com/smiletrl/tss-lib-starter/pkg/grpc/server"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+const offlineUsage = "usage: offline keygen [--key <id>] [--algorithm ecdsa|eddsa] | offline sign --message <text> | --hash <hex> [--key <id>]"
+
+// Offline runs one ceremony on an air-gapped party, messages are exchanged by files carried over by hand, i.e, by
+// USB. It steps through the ceremony round by round: each step ingests the files from other parties within the inbox
+// dir, and lists the files written into the outbox for other parties.
+func Offline(args []string) {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	envPartyID := os.Getenv(constants.EnvPartyID)
+	if envPartyID == "" {
+		panic("env party id is not set yet")
+	}
+	if len(args) == 0 || (args[0] != "keygen" && args[0] != "sign") {
+		log.Fatal(offlineUsage)
+	}
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), offlineUsage) }
+	keyID := fs.String("key", constants.TestKeyID, "id of the key")
+	var algorithm, message, hash *string
+	if args[0] == "keygen" {
+		algorithm = fs.String("algorithm", string(keyAlgorithm()), "algorithm of the new key, ecdsa or eddsa")
+	} else {
+		message = fs.String("message", "", "message to sign, hashed by sha256 for ecdsa")
+		hash = fs.String("hash", "", "hex of the hash to sign as it is, or of the raw message for eddsa")
+	}
+	_ = fs.Parse(args[1:])
+
+	// files for other parties go to `offline/outbox/<party id>`, and files from other parties are put into
+	// `offline/inbox`
+	dir := os.Getenv(constants.EnvOfflineDir)
+	if dir == "" {
+		dir = "offline"
+	}
+	outbox, inbox := filepath.Join(dir, "outbox"), filepath.Join(dir, "inbox")
+
+	cfg := loadConfig()
+	roster := cfg.PartyIDs()
+	id, registry := loadIdentity(envPartyID)
+	client, err := pbClient.NewFileClient(outbox, roster, id, registry)
+	if err != nil {
+		panic("error initializing file client:" + err.Error())
+	}
+
+	// carrying files by hand takes long, so there is no ceremony deadline unless `CEREMONY_TIMEOUT` is set
+	keyStore := newKeyStore()
+	p := party.NewParty(cfg, client, keyStore, newPreParamsPool(), ceremonyTimeout(0))
+	// files carry no handshake, so the config pins the key of every party
+	if err := p.GatherSharedParties(cfg.Parties); err != nil {
+		panic("error gathering shared parties:" + err.Error())
+	}
+	p.SetLocalID(envPartyID)
+
+	in, err := pbServer.NewInbox(inbox, envPartyID, p, id, registry)
+	if err != nil {
+		panic("error initializing inbox:" + err.Error())
+	}
+
+	done := make(chan error, 1)
+	switch args[0] {
+	case "keygen":
+		// ecdsa pre-params are generated before the ceremony starts, so other parties don't wait for them
+		algorithm := constants.Algorithm(*algorithm)
+		if algorithm == constants.AlgorithmECDSA {
+			log.Printf("prepare keygen")
+			if err := p.PrepareKeygen(); err != nil {
+				panic("error preparing keygen:" + err.Error())
+			}
+		}
+		go func() {
+			done <- p.Keygen(context.Background(), ceremony.KeygenSessionID(*keyID), *keyID, algorithm)
+		}()
+	case "sign":
+		// signed the same as the tss cli, so online and offline signers agree on the message data
+		key, err := keyStore.Load(*keyID)
+		if err != nil {
+			log.Fatalf("error loading share of key %s: %v", *keyID, err)
+		}
+		msgData, err := messageData(key.Algorithm, *message, *hash)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			sig, err := p.Sign(context.Background(), ceremony.SigningSessionID(*keyID, signers(), msgData), *keyID, signers(), msgData)
+			if err == nil {
+				log.Printf("signature: %x, recovery: %x", sig.GetSignature(), sig.GetSignatureRecovery())
+			}
+			done <- err
+		}()
+	}
+
+	enter := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			enter <- struct{}{}
+		}
+	}()
+
+	for step := 1; ; step++ {
+		// give the local party a moment to write the messages of this round
+		select {
+		case err := <-done:
+			finishOffline(args[0], outbox, roster, err)
+			return
+		case <-time.After(time.Second):
+		}
+
+		fmt.Printf("\nstep %d\n", step)
+		printOutbox(outbox, roster)
+		fmt.Printf("move the files above to their parties, put the files from other parties into %s, then press enter\n", inbox)
+
+		select {
+		case err := <-done:
+			finishOffline(args[0], outbox, roster, err)
+			return
+		case <-enter:
+		}
+		n, err := in.Ingest(context.Background())
+		if err != nil {
+			log.Printf("error ingesting files: %v", err)
+		}
+		fmt.Printf("%d files ingested\n", n)
+	}
+}
+
+func finishOffline(ceremony, outbox string, roster []string, err error) {
+	if err != nil {
+		log.Fatalf("%s failed: %v", ceremony, err)
+	}
+	// the last messages of this node might still be needed by the slower parties
+	printOutbox(outbox, roster)
+	log.Printf("%s process finished, move the files above to their parties if any", ceremony)
+}
+
+// printOutbox lists the files waiting to be carried over to each party.
+func printOutbox(outbox string, roster []string) {
+	for _, id := range roster {
+		entries, err := os.ReadDir(filepath.Join(outbox, id))
+		if err != nil {
+			log.Printf("error reading outbox of party %s: %v", id, err)
+			continue
+		}
+		var names []string
+		for _, e := range entries {
+			if strings.HasSuffix(e.Name(), pbClient.FrameFileExt) {
+				names = append(names, e.Name())
+			}
+		}
+		if len(names) > 0 {
+			fmt.Printf("  %s: %d files within %s\n", id, len(names), filepath.Join(outbox, id))
+		}
+	}
+}
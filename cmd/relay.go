@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/creds"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/relay"
+)
+
+// Relay runs the relay storing and forwarding messages between parties, for parties which can't dial each other.
+func Relay() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	port := os.Getenv(constants.EnvRelayPort)
+	if port == "" {
+		port = constants.TestRelayPort
+	}
+
+	// parties connect with their own certificates, and the relay presents the certificate for `relay`. The client
+	// certificate tells which party's frames the caller receives, so tls is required.
+	tlsConfig := loadTLSConfig()
+	if !tlsConfig.Enabled() {
+		log.Fatalf("tls is required with relay, set %s, %s and %s", constants.EnvTLSCertFile, constants.EnvTLSKeyFile, constants.EnvTLSCAFile)
+	}
+	roster := loadConfig().PartyIDs()
+	serverCreds, err := creds.ServerCredentials(tlsConfig, roster)
+	if err != nil {
+		panic("error initializing server tls:" + err.Error())
+	}
+
+	if err := relay.Serve(port, serverCreds, roster); err != nil {
+		panic("error serving relay:" + err.Error())
+	}
+}
@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/ceremony"
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/gateway"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/admin"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/creds"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+const tssUsage = `usage: tss [--party <id>] [--env <file>] [--linger <duration>] <command> [flags]
+
+commands:
+  serve     run the node until it's stopped, along with the admin service at ADMIN_ADDR and the http
+            gateway at HTTP_ADDR
+  keygen    generate a new key with the other parties
+  sign      sign a message or hash with the other signers
+  reshare   hand the key shares over to a new committee
+  pubkey    print the shared public key
+  verify    verify a signature against the shared public key
+
+run "tss <command> --help" for the flags of each command.`
+
+// Tss runs one subcommand of the tss cli. Ceremonies run on the local node, so each party runs the same command at
+// about the same time, i.e, `tss --party p1 sign --message hello` on p1, and the same on the other signers.
+func Tss(args []string) {
+	fs := flag.NewFlagSet("tss", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), tssUsage) }
+	partyID := fs.String("party", "", "local party id, PARTY_ID by default")
+	envFile := fs.String("env", "", "env file to load, .env if it exists by default")
+	linger := fs.Duration("linger", 10*time.Second, "how long the node keeps serving after its ceremony, so the slower parties still get its last messages")
+	_ = fs.Parse(args)
+
+	// env vars set by the shell win over the env file
+	if *envFile != "" {
+		if err := godotenv.Load(*envFile); err != nil {
+			log.Fatalf("error loading env file %s: %v", *envFile, err)
+		}
+	} else if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatalf("error loading .env file: %v", err)
+	}
+	if *partyID == "" {
+		*partyID = os.Getenv(constants.EnvPartyID)
+	}
+	if *partyID == "" {
+		log.Fatal("party id is not set, use --party or `PARTY_ID`")
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	c := &tssCmd{partyID: *partyID, linger: *linger}
+	var err error
+	switch command, args := fs.Arg(0), fs.Args()[1:]; command {
+	case "serve":
+		err = c.serve(args)
+	case "keygen":
+		err = c.keygen(args)
+	case "sign":
+		err = c.sign(args)
+	case "reshare":
+		err = c.reshare(args)
+	case "pubkey":
+		err = c.pubkey(args)
+	case "verify":
+		err = c.verify(args)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type tssCmd struct {
+	// local party id
+	partyID string
+
+	// how long the node keeps serving after its ceremony
+	linger time.Duration
+}
+
+// start starts the local node, and checks the local party joins the roster.
+func (c *tssCmd) start() (*node, error) {
+	cfg := loadConfig()
+	if _, err := cfg.Party(c.partyID); err != nil {
+		return nil, err
+	}
+	return startNode(cfg, c.partyID), nil
+}
+
+// finish keeps the node serving for a while, the ceremony is over for this node but the other parties might still be
+// waiting for its last messages.
+func (c *tssCmd) finish() {
+	if c.linger > 0 {
+		log.Printf("keep serving for %s", c.linger)
+		time.Sleep(c.linger)
+	}
+}
+
+func (c *tssCmd) serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	n, err := c.start()
+	if err != nil {
+		return err
+	}
+
+	// ecdsa pre-params are generated right away, so the requested keygens start without waiting for them
+	if keyAlgorithm() == constants.AlgorithmECDSA {
+		go func() {
+			if err := n.party.PrepareKeygen(); err != nil {
+				log.Printf("error preparing keygen: %v", err)
+			}
+		}()
+	}
+
+	// ceremonies are requested by the backend through the admin service, or the http gateway
+	manager := ceremony.NewManager(n.party, n.keyStore, n.id, n.cfg.Threshold)
+	if addr := os.Getenv(constants.EnvAdminAddr); addr != "" {
+		adminCreds, err := creds.ServerCredentials(loadTLSConfig(), []string{admin.ClientName})
+		if err != nil {
+			return fmt.Errorf("error initializing admin tls: %w", err)
+		}
+		go func() {
+			if err := admin.Serve(addr, adminCreds, manager, n.peers); err != nil {
+				panic("error serving admin:" + err.Error())
+			}
+		}()
+	} else {
+		log.Printf("admin service is disabled, set %s to enable it", constants.EnvAdminAddr)
+	}
+	if addr := os.Getenv(constants.EnvHTTPAddr); addr != "" {
+		// the gateway requires the same client certificate as the admin service, with tls
+		var gatewayTLS *tls.Config
+		if tlsConfig := loadTLSConfig(); tlsConfig.Enabled() {
+			if gatewayTLS, err = creds.ServerTLSConfig(tlsConfig, []string{admin.ClientName}); err != nil {
+				return fmt.Errorf("error initializing http gateway tls: %w", err)
+			}
+		}
+		go func() {
+			if err := gateway.Serve(addr, gatewayTLS, manager, n.peers); err != nil {
+				panic("error serving http gateway:" + err.Error())
+			}
+		}()
+	} else {
+		log.Printf("http gateway is disabled, set %s to enable it", constants.EnvHTTPAddr)
+	}
+
+	log.Printf("party %s is serving", c.partyID)
+	select {}
+}
+
+func (c *tssCmd) keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	keyID := fs.String("key", constants.TestKeyID, "id of the new key")
+	algorithm := fs.String("algorithm", string(keyAlgorithm()), "algorithm of the new key, ecdsa or eddsa")
+	_ = fs.Parse(args)
+
+	// keygen overwrites the existing share, which would lose the existing key
+	_, err := newKeyStore().Load(*keyID)
+	switch {
+	case err == nil:
+		return fmt.Errorf("key %s exists already", *keyID)
+	case !errors.Is(err, party.ErrKeyNotFound):
+		return fmt.Errorf("error loading share of key %s: %w", *keyID, err)
+	}
+
+	// ecdsa pre-params are generated and saved before the node starts, so the messages of faster parties aren't
+	// queued here for minutes. They are reused by the next keygen.
+	if constants.Algorithm(*algorithm) == constants.AlgorithmECDSA {
+		log.Printf("prepare keygen pre-params")
+		if err := newPreParamsPool().Prepare(context.Background()); err != nil {
+			return fmt.Errorf("error preparing keygen: %w", err)
+		}
+	}
+
+	n, err := c.start()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("wait for keygen")
+	sessionID := ceremony.KeygenSessionID(*keyID)
+	if err := n.party.Keygen(context.Background(), sessionID, *keyID, constants.Algorithm(*algorithm)); err != nil {
+		return fmt.Errorf("error running keygen: %w", err)
+	}
+	key, err := n.keyStore.Load(*keyID)
+	if err != nil {
+		return fmt.Errorf("error loading share of key %s: %w", *keyID, err)
+	}
+	fmt.Printf("public key: %x\n", key.PublicKey())
+	c.finish()
+	return nil
+}
+
+func (c *tssCmd) sign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyID := fs.String("key", constants.TestKeyID, "id of the key to sign with")
+	message := fs.String("message", "", "message to sign, hashed by sha256 for ecdsa")
+	hash := fs.String("hash", "", "hex of the hash to sign as it is, or of the raw message for eddsa")
+	signerIDs := fs.String("signers", strings.Join(signers(), ","), "comma separated signers, any threshold+1 parties holding the key")
+	_ = fs.Parse(args)
+
+	key, err := newKeyStore().Load(*keyID)
+	if err != nil {
+		return fmt.Errorf("error loading share of key %s: %w", *keyID, err)
+	}
+	msgData, err := messageData(key.Algorithm, *message, *hash)
+	if err != nil {
+		return err
+	}
+	ids := strings.Split(*signerIDs, ",")
+	if !slices.Contains(ids, c.partyID) {
+		return fmt.Errorf("party %s is not one of the signers %s", c.partyID, *signerIDs)
+	}
+
+	n, err := c.start()
+	if err != nil {
+		return err
+	}
+	// all signers derive the same session id from the key, the signers and the message
+	sessionID := ceremony.SigningSessionID(*keyID, ids, msgData)
+	sig, err := n.party.Sign(context.Background(), sessionID, *keyID, ids, msgData)
+	if err != nil {
+		return fmt.Errorf("error signing message: %w", err)
+	}
+	fmt.Printf("signature: %x\n", sig.GetSignature())
+	if key.Algorithm == constants.AlgorithmECDSA {
+		fmt.Printf("recovery: %x\n", sig.GetSignatureRecovery())
+	}
+	c.finish()
+	return nil
+}
+
+func (c *tssCmd) reshare(args []string) error {
+	fs := flag.NewFlagSet("reshare", flag.ExitOnError)
+	keyID := fs.String("key", constants.TestKeyID, "id of the key to reshare")
+	oldIDs := fs.String("old", "", "comma separated parties of the old committee, any threshold+1 parties holding the key")
+	newIDs := fs.String("new", "", "comma separated parties of the new committee")
+	threshold := fs.Int("threshold", 0, "threshold of the new committee, the config threshold by default")
+	_ = fs.Parse(args)
+
+	if *oldIDs == "" || *newIDs == "" {
+		return errors.New("both --old and --new committees are required")
+	}
+	oldCommittee, newCommittee := strings.Split(*oldIDs, ","), strings.Split(*newIDs, ",")
+	if !slices.Contains(oldCommittee, c.partyID) && !slices.Contains(newCommittee, c.partyID) {
+		return fmt.Errorf("party %s joins neither the old nor the new committee", c.partyID)
+	}
+
+	n, err := c.start()
+	if err != nil {
+		return err
+	}
+	if *threshold == 0 {
+		*threshold = n.cfg.Threshold
+	}
+	// all parties derive the same session id, whatever order the committees are listed in
+	sessionID := ceremony.ResharingSessionID(*keyID, oldCommittee, newCommittee, *threshold)
+	if err := n.party.Reshare(context.Background(), sessionID, *keyID, oldCommittee, newCommittee, *threshold); err != nil {
+		return fmt.Errorf("error resharing key %s: %w", *keyID, err)
+	}
+	log.Printf("resharing process finished")
+	c.finish()
+	return nil
+}
+
+func (c *tssCmd) pubkey(args []string) error {
+	fs := flag.NewFlagSet("pubkey", flag.ExitOnError)
+	keyID := fs.String("key", constants.TestKeyID, "id of the key")
+	_ = fs.Parse(args)
+
+	key, err := newKeyStore().Load(*keyID)
+	if err != nil {
+		return fmt.Errorf("error loading share of key %s: %w", *keyID, err)
+	}
+	fmt.Printf("algorithm: %s\n", key.Algorithm)
+	fmt.Printf("public key: %x\n", key.PublicKey())
+	return nil
+}
+
+func (c *tssCmd) verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keyID := fs.String("key", constants.TestKeyID, "id of the key")
+	message := fs.String("message", "", "signed message, hashed by sha256 for ecdsa")
+	hash := fs.String("hash", "", "hex of the signed hash, or of the raw message for eddsa")
+	signature := fs.String("signature", "", "hex of the signature")
+	_ = fs.Parse(args)
+
+	key, err := newKeyStore().Load(*keyID)
+	if err != nil {
+		return fmt.Errorf("error loading share of key %s: %w", *keyID, err)
+	}
+	msgData, err := messageData(key.Algorithm, *message, *hash)
+	if err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(*signature)
+	if err != nil {
+		return fmt.Errorf("error decoding signature: %w", err)
+	}
+	if !key.VerifySignature(msgData, sig) {
+		return errors.New("signature is invalid")
+	}
+	fmt.Println("signature is valid")
+	return nil
+}
+
+// messageData returns the data to sign, from exactly one of the message and the hex hash. Ecdsa signs the sha256 hash
+// of the message, while eddsa signs the message itself.
+func messageData(algorithm constants.Algorithm, message, hash string) ([]byte, error) {
+	switch {
+	case message != "" && hash != "":
+		return nil, errors.New("only one of --message and --hash is allowed")
+	case message != "":
+		if algorithm == constants.AlgorithmECDSA {
+			h := sha256.Sum256([]byte(message))
+			return h[:], nil
+		}
+		return []byte(message), nil
+	case hash != "":
+		data, err := hex.DecodeString(hash)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding hash: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, errors.New("either --message or --hash is required")
+	}
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"os"
+
 	"github.com/smiletrl/tss-lib-starter/cmd"
-	"github.com/smiletrl/tss-lib-starter/pkg/constants"
 )
 
 func main() {
-	cmd.Cmd(constants.SignMessage)
+	cmd.Offline(os.Args[1:])
 }
@@ -0,0 +1,274 @@
+package ceremony
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+const (
+	// keep the finished sessions long enough for their requesters to fetch the results
+	sessionTTL = 24 * time.Hour
+
+	// bound of the kept sessions, the finished ones are dropped first
+	maxSessions = 10000
+)
+
+var (
+	// ErrInvalidRequest is returned when the request is malformed, i.e, a missing key id
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrKeyExists is returned when keygen would overwrite an existing key
+	ErrKeyExists = errors.New("key exists already")
+
+	// ErrSessionNotFound is returned when no session with the given id has been requested on this node
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Manager runs the ceremonies requested on the local node in the background, and keeps their results. Every party
+// of one ceremony has to request it on its own node, they agree on the session id derived from the request.
+//
+// Requesting a running session again returns the same session, and a finished or failed one is run again, as another
+// run of the ceremony.
+type Manager struct {
+	party    party.Party
+	keyStore party.KeyStore
+
+	// local party unique id
+	id string
+
+	// threshold of new committees by default
+	threshold int
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewManager(p party.Party, keyStore party.KeyStore, id string, threshold int) *Manager {
+	return &Manager{
+		party:     p,
+		keyStore:  keyStore,
+		id:        id,
+		threshold: threshold,
+		sessions:  make(map[string]*Session),
+	}
+}
+
+// Keygen starts generating a new key with all parties, and returns the running session.
+func (m *Manager) Keygen(keyID string, algorithm constants.Algorithm) (Session, error) {
+	if keyID == "" {
+		return Session{}, fmt.Errorf("%w: key id is required", ErrInvalidRequest)
+	}
+	if algorithm == "" {
+		algorithm = constants.AlgorithmECDSA
+	}
+	if algorithm != constants.AlgorithmECDSA && algorithm != constants.AlgorithmEdDSA {
+		return Session{}, fmt.Errorf("%w: unexpected key algorithm: %s", ErrInvalidRequest, algorithm)
+	}
+	sessionID := KeygenSessionID(keyID)
+
+	// keygen overwrites the existing share, which would lose the existing key
+	if _, err := m.Key(keyID); err == nil {
+		return Session{}, fmt.Errorf("%w: %s", ErrKeyExists, keyID)
+	} else if !errors.Is(err, party.ErrKeyNotFound) {
+		return Session{}, err
+	}
+	if sess, ok := m.session(sessionID); ok && sess.State == StateRunning {
+		return sess, nil
+	}
+
+	return m.start(sessionID, constants.MessageTypeKeygen, keyID, func(sess *Session) error {
+		if algorithm == constants.AlgorithmECDSA {
+			if err := m.party.PrepareKeygen(); err != nil {
+				return err
+			}
+		}
+		if err := m.party.Keygen(context.Background(), sessionID, keyID, algorithm); err != nil {
+			return err
+		}
+		key, err := m.Key(keyID)
+		if err != nil {
+			return err
+		}
+		sess.PublicKey = key.PublicKey()
+		return nil
+	}), nil
+}
+
+// Sign starts signing the message with the given key by the given signers, and returns the running session. The
+// message is signed as it is, i.e, the 32 bytes hash for ecdsa, or the raw message for eddsa.
+func (m *Manager) Sign(keyID string, signerIDs []string, msgData []byte) (Session, error) {
+	if keyID == "" || len(msgData) == 0 {
+		return Session{}, fmt.Errorf("%w: key id and message are required", ErrInvalidRequest)
+	}
+	if !slices.Contains(signerIDs, m.id) {
+		return Session{}, fmt.Errorf("%w: party %s is not one of the signers %v", ErrInvalidRequest, m.id, signerIDs)
+	}
+	if _, err := m.Key(keyID); err != nil {
+		return Session{}, err
+	}
+	sessionID := SigningSessionID(keyID, signerIDs, msgData)
+	if sess, ok := m.session(sessionID); ok && sess.State == StateRunning {
+		return sess, nil
+	}
+
+	return m.start(sessionID, constants.MessageTypeSigning, keyID, func(sess *Session) error {
+		sig, err := m.party.Sign(context.Background(), sessionID, keyID, signerIDs, msgData)
+		if err != nil {
+			return err
+		}
+		sess.Signature, sess.SignatureRecovery = sig.GetSignature(), sig.GetSignatureRecovery()
+		return nil
+	}), nil
+}
+
+// Reshare starts handing the given key over from the old committee to the new committee, and returns the running
+// session. The new threshold is the config threshold if it's 0.
+func (m *Manager) Reshare(keyID string, oldIDs, newIDs []string, newThreshold int) (Session, error) {
+	if keyID == "" || len(oldIDs) == 0 || len(newIDs) == 0 {
+		return Session{}, fmt.Errorf("%w: key id, old and new committees are required", ErrInvalidRequest)
+	}
+	if !slices.Contains(oldIDs, m.id) && !slices.Contains(newIDs, m.id) {
+		return Session{}, fmt.Errorf("%w: party %s joins neither the old nor the new committee", ErrInvalidRequest, m.id)
+	}
+	if newThreshold == 0 {
+		newThreshold = m.threshold
+	}
+	sessionID := ResharingSessionID(keyID, oldIDs, newIDs, newThreshold)
+	if sess, ok := m.session(sessionID); ok && sess.State == StateRunning {
+		return sess, nil
+	}
+
+	return m.start(sessionID, constants.MessageTypeResharing, keyID, func(*Session) error {
+		return m.party.Reshare(context.Background(), sessionID, keyID, oldIDs, newIDs, newThreshold)
+	}), nil
+}
+
+// Key returns the local share of the given key, party.ErrKeyNotFound if there is none.
+func (m *Manager) Key(keyID string) (*party.KeyShare, error) {
+	key, err := m.keyStore.Load(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading share of key %s: %w", keyID, err)
+	}
+	return key, nil
+}
+
+// Keys returns the ids of all keys this node holds a share of.
+func (m *Manager) Keys() ([]string, error) {
+	return m.keyStore.List()
+}
+
+// Session returns the session with the given id, ErrSessionNotFound if it's never requested on this node.
+func (m *Manager) Session(sessionID string) (Session, error) {
+	sess, ok := m.session(sessionID)
+	if !ok {
+		return Session{}, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	return sess, nil
+}
+
+// Wait waits until the session with the given id is over, or ctx is done.
+func (m *Manager) Wait(ctx context.Context, sessionID string) (Session, error) {
+	m.mu.Lock()
+	sess, ok := m.sessions[sessionID]
+	m.mu.Unlock()
+	if !ok {
+		return Session{}, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	select {
+	case <-sess.done:
+		return m.Session(sessionID)
+	case <-ctx.Done():
+		return Session{}, ctx.Err()
+	}
+}
+
+// session returns a copy of the session with the given id, so it can be read while the ceremony goes on.
+func (m *Manager) session(sessionID string) (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sess, ok := m.sessions[sessionID]
+	if !ok {
+		return Session{}, false
+	}
+	return *sess, true
+}
+
+// start runs the ceremony in the background, and returns its running session. The ceremony sets its result on the
+// session it's given, which is only published once the ceremony is over.
+func (m *Manager) start(sessionID string, msgType constants.MessageType, keyID string, ceremony func(sess *Session) error) Session {
+	sess := &Session{
+		ID:        sessionID,
+		Type:      msgType,
+		KeyID:     keyID,
+		State:     StateRunning,
+		StartedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	if running, ok := m.sessions[sessionID]; ok && running.State == StateRunning {
+		// requested twice at the same time, the first one wins
+		existing := *running
+		m.mu.Unlock()
+		return existing
+	}
+	m.expire(sess.StartedAt)
+	m.sessions[sessionID] = sess
+	started := *sess
+	m.mu.Unlock()
+
+	log.Printf("%s session %s of key %s starts", msgType, sessionID, keyID)
+	go func() {
+		result := *sess
+		err := ceremony(&result)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		result.FinishedAt = time.Now()
+		if err != nil {
+			log.Printf("%s session %s of key %s failed: %v", msgType, sessionID, keyID, err)
+			result.State, result.Err = StateFailed, err
+			var abortErr *party.AbortError
+			if errors.As(err, &abortErr) {
+				result.Culprits = abortErr.Culprits
+			}
+		} else {
+			result.State = StateDone
+		}
+		*sess = result
+		close(sess.done)
+	}()
+	return started
+}
+
+// expire drops the finished sessions older than `sessionTTL`, and then the oldest finished ones if there is still no
+// room. Running sessions are always kept. The caller holds the lock.
+func (m *Manager) expire(now time.Time) {
+	for id, sess := range m.sessions {
+		if sess.State != StateRunning && now.Sub(sess.FinishedAt) >= sessionTTL {
+			delete(m.sessions, id)
+		}
+	}
+	for len(m.sessions) >= maxSessions {
+		oldest := ""
+		for id, sess := range m.sessions {
+			if sess.State != StateRunning && (oldest == "" || sess.FinishedAt.Before(m.sessions[oldest].FinishedAt)) {
+				oldest = id
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		delete(m.sessions, oldest)
+	}
+}
@@ -0,0 +1,69 @@
+package ceremony
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+// State is the state of one ceremony session.
+type State string
+
+const (
+	StateRunning State = "running"
+	StateDone    State = "done"
+	StateFailed  State = "failed"
+)
+
+// Session is one keygen, signing or resharing ceremony requested on this node, along with its result once it's over.
+type Session struct {
+	ID    string
+	Type  constants.MessageType
+	KeyID string
+	State State
+
+	// why the session fails, and the parties to blame if any
+	Err      error
+	Culprits []string
+
+	// shared public key of the generated key, set once keygen is done
+	PublicKey []byte
+
+	// the signature, and the recovery id for ecdsa, set once signing is done
+	Signature         []byte
+	SignatureRecovery []byte
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// closed once the session is over
+	done chan struct{}
+}
+
+// KeygenSessionID returns the session id of the keygen of the given key, the same on all parties.
+func KeygenSessionID(keyID string) string {
+	return party.NewSessionID(constants.MessageTypeKeygen, []byte(keyID))
+}
+
+// SigningSessionID returns the session id of signing the message with the given key by the given signers, the same
+// on all signers whatever order they are listed in. Signing the same message by other signers is another session.
+func SigningSessionID(keyID string, signerIDs []string, msgData []byte) string {
+	signerIDs = slices.Clone(signerIDs)
+	slices.Sort(signerIDs)
+	payload := fmt.Sprintf("%s/%s/", keyID, strings.Join(signerIDs, ","))
+	return party.NewSessionID(constants.MessageTypeSigning, append([]byte(payload), msgData...))
+}
+
+// ResharingSessionID returns the session id of resharing the given key, the same on all parties whatever order the
+// committees are listed in.
+func ResharingSessionID(keyID string, oldIDs, newIDs []string, newThreshold int) string {
+	oldIDs, newIDs = slices.Clone(oldIDs), slices.Clone(newIDs)
+	slices.Sort(oldIDs)
+	slices.Sort(newIDs)
+	payload := fmt.Sprintf("%s/%s/%s/%d", keyID, strings.Join(oldIDs, ","), strings.Join(newIDs, ","), newThreshold)
+	return party.NewSessionID(constants.MessageTypeResharing, []byte(payload))
+}
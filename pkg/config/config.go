@@ -0,0 +1,148 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the deployment of all parties: the roster, the threshold and where each party listens.
+type Config struct {
+	// any threshold+1 parties may sign, at least 1 and less than the number of parties
+	Threshold int `yaml:"threshold"`
+
+	Parties []Party `yaml:"parties"`
+}
+
+// Party is one party of the roster.
+type Party struct {
+	// party unique id, i.e, `p1`
+	ID string `yaml:"id"`
+
+	// optional, parties reached directly announce their own moniker by the handshake. If set, it pins the announced
+	// one.
+	Moniker string `yaml:"moniker"`
+
+	// unique key of this party within tss-lib, never change it once keys are generated. Only the local party needs
+	// it when parties are reached directly, the others announce theirs by the handshake, and the key set here pins
+	// the announced one. Parties behind the relay or offline don't handshake, so all keys are required then.
+	Key string `yaml:"key"`
+
+	// host:port of this party's grpc server, i.e, `127.0.0.1:50051`
+	Address string `yaml:"address"`
+}
+
+// Default returns the test deployment with four local parties, used if no config file is set.
+func Default() *Config {
+	return &Config{
+		Threshold: 2,
+		Parties: []Party{
+			{ID: "p1", Moniker: "tss1", Key: "1", Address: "127.0.0.1:50051"},
+			{ID: "p2", Moniker: "tss2", Key: "2", Address: "127.0.0.1:50052"},
+			{ID: "p3", Moniker: "tss3", Key: "3", Address: "127.0.0.1:50053"},
+			{ID: "p4", Moniker: "tss4", Key: "4", Address: "127.0.0.1:50054"},
+		},
+	}
+}
+
+// Load reads the config from the yaml file, and validates it.
+func Load(path string) (*Config, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	// unknown fields are rejected, so a typo doesn't leave a setting out silently
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(bz))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate makes sure party ids and the keys set are unique, the threshold fits the roster, and addresses are host:port.
+func (c *Config) Validate() error {
+	if len(c.Parties) < 2 {
+		return fmt.Errorf("at least 2 parties are required, got %d", len(c.Parties))
+	}
+	if c.Threshold < 1 || c.Threshold >= len(c.Parties) {
+		return fmt.Errorf("threshold %d must be at least 1 and less than the number of parties %d", c.Threshold, len(c.Parties))
+	}
+
+	ids := make(map[string]struct{}, len(c.Parties))
+	keys := make(map[string]struct{}, len(c.Parties))
+	for i, p := range c.Parties {
+		if p.ID == "" {
+			return fmt.Errorf("party %d: id is required", i)
+		}
+		if _, ok := ids[p.ID]; ok {
+			return fmt.Errorf("party %s: duplicate id", p.ID)
+		}
+		ids[p.ID] = struct{}{}
+
+		if p.Key != "" {
+			if _, ok := keys[p.Key]; ok {
+				return fmt.Errorf("party %s: duplicate key %s", p.ID, p.Key)
+			}
+			keys[p.Key] = struct{}{}
+		}
+
+		if err := validateAddress(p.Address); err != nil {
+			return fmt.Errorf("party %s: %w", p.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if host == "" {
+		return fmt.Errorf("invalid address %q: host is required", address)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid address %q: port must be within 1-65535", address)
+	}
+	return nil
+}
+
+// PartyIDs returns the party unique ids, in the order of the roster.
+func (c *Config) PartyIDs() []string {
+	ids := make([]string, len(c.Parties))
+	for i, p := range c.Parties {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+// Party returns the party with the given unique id.
+func (c *Config) Party(id string) (Party, error) {
+	for _, p := range c.Parties {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return Party{}, errors.New("unexpected party unique id: " + id)
+}
+
+// Port returns the port the party with the given unique id listens at.
+func (c *Config) Port(id string) (string, error) {
+	p, err := c.Party(id)
+	if err != nil {
+		return "", err
+	}
+	_, port, err := net.SplitHostPort(p.Address)
+	return port, err
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		// substring of the expected error, empty if the config is valid
+		err string
+	}{
+		{name: "default", modify: func(c *Config) {}},
+		{name: "empty key", modify: func(c *Config) { c.Parties[0].Key = "" }},
+		{name: "duplicate id", modify: func(c *Config) { c.Parties[1].ID = "p1" }, err: "duplicate id"},
+		{name: "missing id", modify: func(c *Config) { c.Parties[1].ID = "" }, err: "id is required"},
+		{name: "duplicate key", modify: func(c *Config) { c.Parties[1].Key = "1" }, err: "duplicate key"},
+		{name: "one party", modify: func(c *Config) { c.Parties = c.Parties[:1] }, err: "at least 2 parties"},
+		{name: "threshold equals parties", modify: func(c *Config) { c.Threshold = 4 }, err: "threshold"},
+		{name: "threshold above parties", modify: func(c *Config) { c.Threshold = 5 }, err: "threshold"},
+		{name: "zero threshold", modify: func(c *Config) { c.Threshold = 0 }, err: "threshold"},
+		{name: "negative threshold", modify: func(c *Config) { c.Threshold = -1 }, err: "threshold"},
+		{name: "missing port", modify: func(c *Config) { c.Parties[0].Address = "127.0.0.1" }, err: "invalid address"},
+		{name: "missing host", modify: func(c *Config) { c.Parties[0].Address = ":50051" }, err: "host is required"},
+		{name: "empty address", modify: func(c *Config) { c.Parties[0].Address = "" }, err: "invalid address"},
+		{name: "non numeric port", modify: func(c *Config) { c.Parties[0].Address = "127.0.0.1:grpc" }, err: "port must be"},
+		{name: "zero port", modify: func(c *Config) { c.Parties[0].Address = "127.0.0.1:0" }, err: "port must be"},
+		{name: "port out of range", modify: func(c *Config) { c.Parties[0].Address = "127.0.0.1:65536" }, err: "port must be"},
+		{name: "ipv6 address", modify: func(c *Config) { c.Parties[0].Address = "[::1]:50051" }},
+		{name: "host name", modify: func(c *Config) { c.Parties[0].Address = "tss1:50051" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.modify(c)
+			err := c.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,62 +1,77 @@
 package constants
 
-const (
-	TestParticipants = 4
-	TestThreshold    = 2
-)
-
-var (
-	TestPartyIdentifiers = []PartyIdentifier{
-		{
-			ID:      "p1",
-			Moniker: "tss1",
-			Key:     "1",
-		},
-		{
-			ID:      "p2",
-			Moniker: "tss2",
-			Key:     "2",
-		},
-		{
-			ID:      "p3",
-			Moniker: "tss3",
-			Key:     "3",
-		},
-		{
-			ID:      "p4",
-			Moniker: "tss4",
-			Key:     "4",
-		},
-	}
-
-	TestGrpcHost = map[string][2]string{
-		"p1": {"127.0.0.1", "50051"},
-		"p2": {"127.0.0.1", "50052"},
-		"p3": {"127.0.0.1", "50053"},
-		"p4": {"127.0.0.1", "50054"},
-	}
-
-	// !Important The unselected party must come from the last one(i.e, p4, p3, etc) because of
-	// this test env to use sorted parties
-	SelectedParties = map[string]struct{}{
-		"p1": {},
-		"p2": {},
-		"p3": {},
-		"p4": {},
-	}
-)
+import "time"
 
-type PartyIdentifier struct {
-	ID, Moniker, Key string
-}
+// default signers of the test message, any threshold+1 of all parties in any order. `SIGNERS` overrides it, i.e,
+// `SIGNERS=p4,p2,p1`
+var TestSigners = []string{"p1", "p2", "p3", "p4"}
 
 var EnvPartyID string = "PARTY_ID"
 
+var EnvConfigFile string = "CONFIG_FILE"
+
+var EnvKeyAlgorithm string = "KEY_ALGORITHM"
+
+var EnvKeyStoreDir string = "KEY_STORE_DIR"
+
+var EnvPreParamsDir string = "PRE_PARAMS_DIR"
+
+var EnvPreParamsPoolSize string = "PRE_PARAMS_POOL_SIZE"
+
+var EnvCeremonyTimeout string = "CEREMONY_TIMEOUT"
+
+var EnvSigners string = "SIGNERS"
+
+var EnvTLSCertFile string = "TLS_CERT_FILE"
+
+var EnvTLSKeyFile string = "TLS_KEY_FILE"
+
+var EnvTLSCAFile string = "TLS_CA_FILE"
+
+var EnvIdentityFile string = "IDENTITY_FILE"
+
+var EnvIdentityRegistryFile string = "IDENTITY_REGISTRY_FILE"
+
+var EnvRelayAddr string = "RELAY_ADDR"
+
+var EnvRelayPort string = "RELAY_PORT"
+
+// TestRelayPort is the local port of the relay, unless `RELAY_PORT` is set.
+var TestRelayPort string = "50050"
+
+var EnvOfflineDir string = "OFFLINE_DIR"
+
+var EnvAdminAddr string = "ADMIN_ADDR"
+
+var EnvHTTPAddr string = "HTTP_ADDR"
+
+// EnvRosterFile is the file the roster agreed by the handshake is saved into, `roster.json` by default.
+var EnvRosterFile string = "ROSTER_FILE"
+
+// DefaultCeremonyTimeout is the deadline of one keygen/signing/resharing ceremony, unless `CEREMONY_TIMEOUT` is set.
+const DefaultCeremonyTimeout = 5 * time.Minute
+
 var SignMessage string = "hey this is a test"
 
+var TestKeyID string = "test-key"
+
+// Algorithm is the signature scheme of one shared key.
+type Algorithm string
+
+const (
+	// secp256k1 keys, like Bitcoin, Ethereum
+	AlgorithmECDSA Algorithm = "ecdsa"
+	// Ed25519 keys, like Solana, Cosmos
+	AlgorithmEdDSA Algorithm = "eddsa"
+)
+
 type MessageType string
 
 const (
-	MessageTypeKeygen  MessageType = "keygen"
-	MessageTypeSigning MessageType = "signing"
+	MessageTypeKeygen    MessageType = "keygen"
+	MessageTypeSigning   MessageType = "signing"
+	MessageTypeResharing MessageType = "resharing"
+
+	// nonces starting one run of any ceremony, its run id is derived from them
+	MessageTypeNonce MessageType = "nonce"
 )
@@ -0,0 +1,403 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/config"
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
+)
+
+// retry interval of the handshakes and the roster signatures, while some parties are still down
+const retryInterval = 2 * time.Second
+
+// ErrNotReady is returned when this node can't sign the roster yet, since not all parties have announced themselves.
+var ErrNotReady = errors.New("not all parties have announced themselves yet")
+
+// Discovery agrees on the roster of all ceremonies with the other parties. The node serves meanwhile, keygen and
+// resharing wait until the roster is agreed.
+//
+// The config only tells the party unique ids and the addresses to reach each party. Each party announces itself to
+// all others with a signed `Hello`, carrying its moniker, its unique key within tss-lib, its address and its identity
+// keys. Once all parties have announced themselves, each of them builds the same roster, and signs it. The roster is
+// agreed once it's signed by all parties, and saved, so a restarted node doesn't wait for all parties again.
+type Discovery struct {
+	cfg *config.Config
+
+	// signed announcement of the local party
+	self *pb.Hello
+
+	// identity key signing the local announcement and the roster
+	identity *identity.Identity
+
+	// identity keys of all parties, the announced identity keys must match them
+	registry identity.Registry
+
+	client client.Handshaker
+
+	// file the agreed roster is saved into
+	file string
+
+	mu sync.Mutex
+
+	// announcements of all parties, key is party unique id
+	hellos map[string]*pb.Hello
+
+	// the agreed roster, nil until all parties have signed it
+	roster *pb.Roster
+}
+
+// New returns the discovery of the local party. The identity and the registry are required, every announcement and
+// roster signature is verified against the registered identity keys.
+func New(cfg *config.Config, id string, identity *identity.Identity, registry identity.Registry, client client.Handshaker, file string) (*Discovery, error) {
+	if identity == nil || registry == nil {
+		return nil, errors.New("identity and identity registry are required to handshake")
+	}
+	local, err := cfg.Party(id)
+	if err != nil {
+		return nil, err
+	}
+	if local.Key == "" {
+		return nil, fmt.Errorf("party %s: key is required for the local party", id)
+	}
+	entry := identity.Entry()
+	self := &pb.Hello{
+		PartyId: local.ID,
+		Moniker: local.Moniker,
+		Key:     local.Key,
+		Address: local.Address,
+		SignKey: entry.SignKey,
+		BoxKey:  entry.BoxKey,
+	}
+	self.Signature = identity.Sign(self.SigningBytes())
+	return &Discovery{
+		cfg:      cfg,
+		self:     self,
+		identity: identity,
+		registry: registry,
+		client:   client,
+		file:     file,
+		hellos:   map[string]*pb.Hello{id: self},
+	}, nil
+}
+
+// Run returns the agreed roster. It loads the saved roster if it still fits the config, otherwise it announces the
+// local party to all others and collects their signatures, retrying until all parties are up or ctx is done.
+func (d *Discovery) Run(ctx context.Context) ([]config.Party, error) {
+	roster, err := d.load()
+	if err != nil {
+		log.Printf("saved roster is not used, agree on a new one: %v", err)
+		if roster, err = d.agree(ctx); err != nil {
+			return nil, err
+		}
+		if err := d.save(roster); err != nil {
+			return nil, err
+		}
+	}
+
+	d.mu.Lock()
+	d.roster = roster
+	for _, h := range roster.GetParties() {
+		d.hellos[h.GetPartyId()] = h
+	}
+	d.mu.Unlock()
+
+	log.Printf("roster of %d parties with threshold %d is agreed", len(roster.GetParties()), roster.GetThreshold())
+	return Parties(roster), nil
+}
+
+// Parties returns the parties of the roster, in the order of the roster.
+func Parties(roster *pb.Roster) []config.Party {
+	parties := make([]config.Party, len(roster.GetParties()))
+	for i, h := range roster.GetParties() {
+		parties[i] = config.Party{
+			ID:      h.GetPartyId(),
+			Moniker: h.GetMoniker(),
+			Key:     h.GetKey(),
+			Address: h.GetAddress(),
+		}
+	}
+	return parties
+}
+
+// OnHandshake records the announcement of the party, and returns the local one. peer is the party of the client
+// certificate, empty without tls. An announcement must come from its own party, by its signature of the identity key
+// the registry pins, and by the client certificate with tls. Otherwise any caller could announce itself as another
+// party before the roster is agreed.
+func (d *Discovery) OnHandshake(ctx context.Context, peer string, hello *pb.Hello) (*pb.Hello, error) {
+	if peer != "" && peer != hello.GetPartyId() {
+		return nil, fmt.Errorf("party %s is not allowed to announce party %s", peer, hello.GetPartyId())
+	}
+	if err := d.record(hello); err != nil {
+		return nil, err
+	}
+	return d.self, nil
+}
+
+// OnSignRoster signs the roster if it's the same as the one built by this node, ErrNotReady if this node can't
+// build it yet.
+func (d *Discovery) OnSignRoster(ctx context.Context, roster *pb.Roster) (*pb.RosterSignature, error) {
+	d.mu.Lock()
+	own := d.roster
+	if own == nil {
+		own = d.build()
+	}
+	d.mu.Unlock()
+
+	if own == nil {
+		return nil, ErrNotReady
+	}
+	if !bytes.Equal(own.SigningBytes(), roster.SigningBytes()) {
+		return nil, errors.New("roster differs from the one built by this party")
+	}
+	return d.sign(own), nil
+}
+
+// agree announces the local party to all others until all of them have announced themselves, and then collects their
+// signatures over the roster.
+func (d *Discovery) agree(ctx context.Context) (*pb.Roster, error) {
+	peers := d.peers()
+	if err := d.retry(ctx, "handshake", peers, func(pid string) error {
+		hello, err := d.client.Handshake(ctx, pid, d.self)
+		if err != nil {
+			return err
+		}
+		if hello.GetPartyId() != pid {
+			return fmt.Errorf("unexpected announcement of party %s", hello.GetPartyId())
+		}
+		return d.record(hello)
+	}); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	roster := d.build()
+	d.mu.Unlock()
+	if roster == nil {
+		return nil, ErrNotReady
+	}
+
+	signatures := map[string]*pb.RosterSignature{d.self.GetPartyId(): d.sign(roster)}
+	var mu sync.Mutex
+	if err := d.retry(ctx, "roster signature", peers, func(pid string) error {
+		sig, err := d.client.SignRoster(ctx, pid, roster)
+		if err != nil {
+			return err
+		}
+		if sig.GetPartyId() != pid {
+			return fmt.Errorf("unexpected roster signature of party %s", sig.GetPartyId())
+		}
+		mu.Lock()
+		signatures[pid] = sig
+		mu.Unlock()
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, h := range roster.GetParties() {
+		roster.Signatures = append(roster.Signatures, signatures[h.GetPartyId()])
+	}
+	if err := d.verifyRoster(roster); err != nil {
+		return nil, err
+	}
+	return roster, nil
+}
+
+// retry calls each party until the call succeeds, or ctx is done.
+func (d *Discovery) retry(ctx context.Context, name string, pids []string, call func(pid string) error) error {
+	pending := slices.Clone(pids)
+	for len(pending) > 0 {
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			failed []string
+		)
+		for _, pid := range pending {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := call(pid); err != nil {
+					log.Printf("error on %s with party %s, retry in %s: %v", name, pid, retryInterval, err)
+					mu.Lock()
+					failed = append(failed, pid)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if pending = failed; len(pending) == 0 {
+			return nil
+		}
+		log.Printf("wait for %s with parties %s", name, strings.Join(pending, ","))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error on %s with parties %v: %w", name, pending, ctx.Err())
+		case <-time.After(retryInterval):
+		}
+	}
+	return nil
+}
+
+// record verifies the announcement of the party, and keeps it. Once the roster is agreed, a party can't announce
+// anything else.
+func (d *Discovery) record(hello *pb.Hello) error {
+	if err := d.verifyHello(hello); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.roster != nil {
+		agreed := d.hellos[hello.GetPartyId()]
+		if !bytes.Equal(agreed.SigningBytes(), hello.SigningBytes()) {
+			return fmt.Errorf("announcement of party %s differs from the agreed roster", hello.GetPartyId())
+		}
+		return nil
+	}
+	d.hellos[hello.GetPartyId()] = hello
+	return nil
+}
+
+// verifyHello makes sure the announcement comes from a party of the config, matches what the config pins, and is
+// signed by the announced identity key.
+func (d *Discovery) verifyHello(hello *pb.Hello) error {
+	pid := hello.GetPartyId()
+	expected, err := d.cfg.Party(pid)
+	if err != nil {
+		return err
+	}
+	if hello.GetKey() == "" {
+		return fmt.Errorf("party %s announces no key", pid)
+	}
+	if expected.Key != "" && expected.Key != hello.GetKey() {
+		return fmt.Errorf("party %s announces key %s, config pins %s", pid, hello.GetKey(), expected.Key)
+	}
+	if expected.Moniker != "" && expected.Moniker != hello.GetMoniker() {
+		return fmt.Errorf("party %s announces moniker %s, config pins %s", pid, hello.GetMoniker(), expected.Moniker)
+	}
+	if expected.Address != hello.GetAddress() {
+		return fmt.Errorf("party %s announces address %s, config has %s", pid, hello.GetAddress(), expected.Address)
+	}
+
+	// parties sign their announcements, and the registry pins their identity keys
+	entry, ok := d.registry[pid]
+	if !ok {
+		return fmt.Errorf("unregistered party: %s", pid)
+	}
+	if !bytes.Equal(entry.SignKey, hello.GetSignKey()) || !bytes.Equal(entry.BoxKey, hello.GetBoxKey()) {
+		return fmt.Errorf("party %s announces identity keys other than the registered ones", pid)
+	}
+	if err := d.registry.Verify(pid, hello.SigningBytes(), hello.GetSignature()); err != nil {
+		return fmt.Errorf("invalid announcement signature of party %s: %w", pid, err)
+	}
+	return nil
+}
+
+// build returns the roster from the announcements of all parties, or nil if some parties haven't announced
+// themselves yet. The caller holds the lock.
+func (d *Discovery) build() *pb.Roster {
+	roster := &pb.Roster{Threshold: int32(d.cfg.Threshold)}
+	for _, pid := range d.cfg.PartyIDs() {
+		hello, ok := d.hellos[pid]
+		if !ok {
+			return nil
+		}
+		roster.Parties = append(roster.Parties, hello)
+	}
+	slices.SortFunc(roster.Parties, func(a, b *pb.Hello) int {
+		return strings.Compare(a.GetPartyId(), b.GetPartyId())
+	})
+	return roster
+}
+
+func (d *Discovery) sign(roster *pb.Roster) *pb.RosterSignature {
+	return &pb.RosterSignature{
+		PartyId:   d.self.GetPartyId(),
+		Signature: d.identity.Sign(roster.SigningBytes()),
+	}
+}
+
+// verifyRoster makes sure the roster has all parties of the config with the same threshold, the local party is
+// announced as it is, and every party has signed it.
+func (d *Discovery) verifyRoster(roster *pb.Roster) error {
+	if int(roster.GetThreshold()) != d.cfg.Threshold {
+		return fmt.Errorf("roster threshold %d differs from config threshold %d", roster.GetThreshold(), d.cfg.Threshold)
+	}
+	ids := d.cfg.PartyIDs()
+	if len(roster.GetParties()) != len(ids) || len(roster.GetSignatures()) != len(ids) {
+		return fmt.Errorf("roster of %d parties differs from config of %d parties", len(roster.GetParties()), len(ids))
+	}
+	data := roster.SigningBytes()
+	for i, h := range roster.GetParties() {
+		if i > 0 && roster.GetParties()[i-1].GetPartyId() >= h.GetPartyId() {
+			return errors.New("roster parties are not sorted by party unique id")
+		}
+		if err := d.verifyHello(h); err != nil {
+			return err
+		}
+		if h.GetPartyId() == d.self.GetPartyId() && !proto.Equal(h, d.self) {
+			return errors.New("roster announcement of the local party differs from the local one")
+		}
+
+		sig := roster.GetSignatures()[i]
+		if sig.GetPartyId() != h.GetPartyId() {
+			return fmt.Errorf("unexpected roster signature of party %s", sig.GetPartyId())
+		}
+		if err := d.registry.Verify(h.GetPartyId(), data, sig.GetSignature()); err != nil {
+			return fmt.Errorf("invalid roster signature of party %s: %w", h.GetPartyId(), err)
+		}
+	}
+	return nil
+}
+
+// load loads the saved roster, and makes sure it still fits the config and the local identity.
+func (d *Discovery) load() (*pb.Roster, error) {
+	bz, err := os.ReadFile(d.file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading roster: %w", err)
+	}
+	roster := &pb.Roster{}
+	if err := protojson.Unmarshal(bz, roster); err != nil {
+		return nil, fmt.Errorf("error unmarshaling roster: %w", err)
+	}
+	if err := d.verifyRoster(roster); err != nil {
+		return nil, err
+	}
+	return roster, nil
+}
+
+func (d *Discovery) save(roster *pb.Roster) error {
+	bz, err := protojson.MarshalOptions{Multiline: true}.Marshal(roster)
+	if err != nil {
+		return fmt.Errorf("error marshaling roster: %w", err)
+	}
+	if err := os.WriteFile(d.file, bz, 0644); err != nil {
+		return fmt.Errorf("error writing roster: %w", err)
+	}
+	return nil
+}
+
+// peers returns the party unique ids of all other parties.
+func (d *Discovery) peers() []string {
+	var peers []string
+	for _, pid := range d.cfg.PartyIDs() {
+		if pid != d.self.GetPartyId() {
+			peers = append(peers, pid)
+		}
+	}
+	return peers
+}
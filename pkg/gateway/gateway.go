@@ -0,0 +1,357 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/ceremony"
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+const (
+	// bound of one long-poll, the client polls again if the session is still running
+	maxWait = 5 * time.Minute
+
+	// interval of the keep-alive comments of the event stream, so proxies don't close it while the session runs
+	heartbeat = 15 * time.Second
+)
+
+// Serve runs the http/json gateway at the given address, i.e, `127.0.0.1:50071`. It drives ceremonies on this node
+// like the admin service, so it should only be reachable by the backend. With tls config set, it requires the client
+// certificate checked by the tls config.
+//
+//	POST /v1/keys                    start keygen, `{"key_id", "algorithm"}`
+//	GET  /v1/keys                    list all keys
+//	GET  /v1/keys/{key_id}           the shared public key
+//	POST /v1/keys/{key_id}/sign      start signing, `{"hash", "signers"}`
+//	GET  /v1/sessions/{id}?wait=30s  session status, waiting up to `wait` until it's over
+//	GET  /v1/sessions/{id}/events    session status as server-sent events, until it's over
+//	GET  /v1/peers                   connectivity to the other parties, not found if peers is nil
+func Serve(addr string, tlsConfig *tls.Config, manager *ceremony.Manager, peers client.StatusReporter) error {
+	log.Printf("http gateway listens at: %v", addr)
+
+	g := &gateway{manager: manager, peers: peers}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/keys", g.keygen)
+	mux.HandleFunc("GET /v1/keys", g.listKeys)
+	mux.HandleFunc("GET /v1/keys/{key_id}", g.getKey)
+	mux.HandleFunc("POST /v1/keys/{key_id}/sign", g.sign)
+	mux.HandleFunc("GET /v1/sessions/{id}", g.getSession)
+	mux.HandleFunc("GET /v1/sessions/{id}/events", g.sessionEvents)
+	if peers != nil {
+		mux.HandleFunc("GET /v1/peers", g.listPeers)
+	}
+
+	// no write timeout, long-polls and event streams stay open until the session is over
+	s := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	if tlsConfig != nil {
+		return s.ListenAndServeTLS("", "")
+	}
+	return s.ListenAndServe()
+}
+
+type gateway struct {
+	manager *ceremony.Manager
+	peers   client.StatusReporter
+}
+
+type keygenRequest struct {
+	KeyID string `json:"key_id"`
+
+	// ecdsa or eddsa, ecdsa by default
+	Algorithm string `json:"algorithm"`
+}
+
+type signRequest struct {
+	// hex of the data signed as it is, i.e, the 32 bytes hash for ecdsa, or the raw message for eddsa
+	Hash string `json:"hash"`
+
+	// party unique ids of the signers, any threshold+1 parties holding the key in any order
+	Signers []string `json:"signers"`
+}
+
+type keyResponse struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+
+	// hex of the 33 bytes compressed point for ecdsa, or the 32 bytes ed25519 public key for eddsa
+	PublicKey string `json:"public_key"`
+
+	Parties   []string `json:"parties"`
+	Threshold int      `json:"threshold"`
+}
+
+type sessionResponse struct {
+	SessionID string         `json:"session_id"`
+	Type      string         `json:"type"`
+	KeyID     string         `json:"key_id"`
+	State     ceremony.State `json:"state"`
+
+	Error    string   `json:"error,omitempty"`
+	Culprits []string `json:"culprits,omitempty"`
+
+	// hex of the shared public key, set once keygen is done
+	PublicKey string `json:"public_key,omitempty"`
+
+	// hex of `r || s` and the recovery id for ecdsa, or of the 64 bytes ed25519 signature for eddsa, set once
+	// signing is done
+	Signature         string `json:"signature,omitempty"`
+	SignatureRecovery string `json:"signature_recovery,omitempty"`
+
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type peerResponse struct {
+	PartyID string `json:"party_id"`
+
+	// grpc connectivity state of the direct connection, i.e, `READY`, empty through relay
+	State string `json:"state,omitempty"`
+
+	// why the last message to this party fails after all retries
+	LastError string `json:"last_error,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (g *gateway) keygen(w http.ResponseWriter, r *http.Request) {
+	var req keygenRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	sess, err := g.manager.Keygen(req.KeyID, constants.Algorithm(req.Algorithm))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, toSession(sess))
+}
+
+func (g *gateway) sign(w http.ResponseWriter, r *http.Request) {
+	var req signRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	msgData, err := hex.DecodeString(req.Hash)
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: error decoding hash: %v", ceremony.ErrInvalidRequest, err))
+		return
+	}
+	sess, err := g.manager.Sign(r.PathValue("key_id"), req.Signers, msgData)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, toSession(sess))
+}
+
+func (g *gateway) listKeys(w http.ResponseWriter, r *http.Request) {
+	keyIDs, err := g.manager.Keys()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	keys := make([]keyResponse, 0, len(keyIDs))
+	for _, keyID := range keyIDs {
+		key, err := g.manager.Key(keyID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		keys = append(keys, toKey(keyID, key))
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (g *gateway) getKey(w http.ResponseWriter, r *http.Request) {
+	keyID := r.PathValue("key_id")
+	key, err := g.manager.Key(keyID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toKey(keyID, key))
+}
+
+func (g *gateway) listPeers(w http.ResponseWriter, r *http.Request) {
+	statuses := g.peers.Status()
+	peers := make([]peerResponse, 0, len(statuses))
+	for _, st := range statuses {
+		peers = append(peers, peerResponse{
+			PartyID:   st.PartyID,
+			State:     st.State,
+			LastError: st.LastError,
+		})
+	}
+	writeJSON(w, http.StatusOK, peers)
+}
+
+// getSession returns the session right away, or with `wait` set, once it's over or `wait` has passed, whichever
+// comes first.
+func (g *gateway) getSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, err := g.manager.Session(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if wait := r.URL.Query().Get("wait"); wait != "" && sess.State == ceremony.StateRunning {
+		d, err := time.ParseDuration(wait)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: error parsing wait: %v", ceremony.ErrInvalidRequest, err))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), min(d, maxWait))
+		defer cancel()
+		if sess, err = g.manager.Wait(ctx, id); errors.Is(err, context.DeadlineExceeded) {
+			sess, err = g.manager.Session(id)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, toSession(sess))
+}
+
+// sessionEvents streams the session as server-sent events, one `session` event right away and another once it's
+// over, then the stream ends.
+func (g *gateway) sessionEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, err := g.manager.Session(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	writeEvent(w, sess)
+	flusher.Flush()
+	if sess.State != ceremony.StateRunning {
+		return
+	}
+
+	done := make(chan ceremony.Session, 1)
+	go func() {
+		if sess, err := g.manager.Wait(r.Context(), id); err == nil {
+			done <- sess
+		}
+	}()
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case sess := <-done:
+			writeEvent(w, sess)
+			flusher.Flush()
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, sess ceremony.Session) {
+	data, err := json.Marshal(toSession(sess))
+	if err != nil {
+		log.Printf("error encoding session %s: %v", sess.ID, err)
+		return
+	}
+	fmt.Fprintf(w, "event: session\ndata: %s\n\n", data)
+}
+
+func decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: error decoding request: %v", ceremony.ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error writing response: %v", err)
+	}
+}
+
+// writeError maps the manager errors to http status codes.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ceremony.ErrInvalidRequest):
+		code = http.StatusBadRequest
+	case errors.Is(err, ceremony.ErrKeyExists):
+		code = http.StatusConflict
+	case errors.Is(err, ceremony.ErrSessionNotFound), errors.Is(err, party.ErrKeyNotFound):
+		code = http.StatusNotFound
+	}
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
+
+func toKey(keyID string, key *party.KeyShare) keyResponse {
+	parties := make([]string, 0, len(key.PartyIDs))
+	for _, pid := range key.PartyIDs {
+		parties = append(parties, pid.GetId())
+	}
+	return keyResponse{
+		KeyID:     keyID,
+		Algorithm: string(key.Algorithm),
+		PublicKey: hex.EncodeToString(key.PublicKey()),
+		Parties:   parties,
+		Threshold: key.Threshold,
+	}
+}
+
+func toSession(sess ceremony.Session) sessionResponse {
+	s := sessionResponse{
+		SessionID:         sess.ID,
+		Type:              string(sess.Type),
+		KeyID:             sess.KeyID,
+		State:             sess.State,
+		Culprits:          sess.Culprits,
+		PublicKey:         hex.EncodeToString(sess.PublicKey),
+		Signature:         hex.EncodeToString(sess.Signature),
+		SignatureRecovery: hex.EncodeToString(sess.SignatureRecovery),
+		StartedAt:         sess.StartedAt,
+	}
+	if sess.Err != nil {
+		s.Error = sess.Err.Error()
+	}
+	if !sess.FinishedAt.IsZero() {
+		s.FinishedAt = &sess.FinishedAt
+	}
+	return s
+}
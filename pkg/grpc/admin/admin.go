@@ -0,0 +1,194 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/ceremony"
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+// ClientName is the name of the certificate the backend presents to the admin service, with tls
+const ClientName = "admin"
+
+// Serve runs the admin service at the given address, i.e, `127.0.0.1:50061`. It drives ceremonies on this node, so
+// it should only be reachable by the backend, and requires the client certificate for `ClientName` with tls. Peers
+// report the connectivity to the other parties, `ListPeers` is unimplemented if nil.
+func Serve(addr string, creds credentials.TransportCredentials, manager *ceremony.Manager, peers client.StatusReporter) error {
+	log.Printf("admin service listens at: %v", addr)
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s := grpc.NewServer(grpc.Creds(creds))
+	pb.RegisterAdminServer(s, &server{manager: manager, peers: peers})
+
+	// let grpcurl and alike list the admin methods
+	reflection.Register(s)
+	return s.Serve(lis)
+}
+
+// server is rpc server for admin
+type server struct {
+	pb.UnimplementedAdminServer
+	manager *ceremony.Manager
+	peers   client.StatusReporter
+}
+
+func (s *server) StartKeygen(ctx context.Context, req *pb.KeygenRequest) (*pb.Session, error) {
+	sess, err := s.manager.Keygen(req.GetKeyId(), constants.Algorithm(req.GetAlgorithm()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toSession(sess), nil
+}
+
+func (s *server) Sign(ctx context.Context, req *pb.SignRequest) (*pb.Session, error) {
+	sess, err := s.manager.Sign(req.GetKeyId(), req.GetSigners(), req.GetMessage())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if req.GetAsync() {
+		return toSession(sess), nil
+	}
+
+	// the ceremony goes on if the caller gives up waiting, its result is kept for `GetSession`
+	if sess, err = s.manager.Wait(ctx, sess.ID); err != nil {
+		return nil, toStatus(err)
+	}
+	return toSession(sess), nil
+}
+
+func (s *server) Reshare(ctx context.Context, req *pb.ReshareRequest) (*pb.Session, error) {
+	sess, err := s.manager.Reshare(req.GetKeyId(), req.GetOldParties(), req.GetNewParties(), int(req.GetThreshold()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toSession(sess), nil
+}
+
+func (s *server) GetPublicKey(ctx context.Context, req *pb.GetPublicKeyRequest) (*pb.Key, error) {
+	key, err := s.manager.Key(req.GetKeyId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toKey(req.GetKeyId(), key), nil
+}
+
+func (s *server) ListKeys(ctx context.Context, _ *emptypb.Empty) (*pb.ListKeysResponse, error) {
+	keyIDs, err := s.manager.Keys()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListKeysResponse{Keys: make([]*pb.Key, 0, len(keyIDs))}
+	for _, keyID := range keyIDs {
+		key, err := s.manager.Key(keyID)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		resp.Keys = append(resp.Keys, toKey(keyID, key))
+	}
+	return resp, nil
+}
+
+func (s *server) GetSession(ctx context.Context, req *pb.GetSessionRequest) (*pb.Session, error) {
+	sess, err := s.manager.Session(req.GetSessionId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toSession(sess), nil
+}
+
+func (s *server) ListPeers(ctx context.Context, _ *emptypb.Empty) (*pb.ListPeersResponse, error) {
+	if s.peers == nil {
+		return nil, status.Error(codes.Unimplemented, "peer status is not reported by this node")
+	}
+	statuses := s.peers.Status()
+	resp := &pb.ListPeersResponse{Peers: make([]*pb.Peer, 0, len(statuses))}
+	for _, st := range statuses {
+		resp.Peers = append(resp.Peers, &pb.Peer{
+			PartyId:   st.PartyID,
+			State:     st.State,
+			LastError: st.LastError,
+		})
+	}
+	return resp, nil
+}
+
+func toKey(keyID string, key *party.KeyShare) *pb.Key {
+	parties := make([]string, 0, len(key.PartyIDs))
+	for _, pid := range key.PartyIDs {
+		parties = append(parties, pid.GetId())
+	}
+	return &pb.Key{
+		KeyId:     keyID,
+		Algorithm: string(key.Algorithm),
+		PublicKey: key.PublicKey(),
+		Parties:   parties,
+		Threshold: int32(key.Threshold),
+	}
+}
+
+func toSession(sess ceremony.Session) *pb.Session {
+	s := &pb.Session{
+		SessionId:         sess.ID,
+		Type:              string(sess.Type),
+		KeyId:             sess.KeyID,
+		Culprits:          sess.Culprits,
+		PublicKey:         sess.PublicKey,
+		Signature:         sess.Signature,
+		SignatureRecovery: sess.SignatureRecovery,
+		StartedAt:         timestamp(sess.StartedAt),
+		FinishedAt:        timestamp(sess.FinishedAt),
+	}
+	switch sess.State {
+	case ceremony.StateRunning:
+		s.State = pb.SessionState_SESSION_STATE_RUNNING
+	case ceremony.StateDone:
+		s.State = pb.SessionState_SESSION_STATE_DONE
+	case ceremony.StateFailed:
+		s.State = pb.SessionState_SESSION_STATE_FAILED
+	}
+	if sess.Err != nil {
+		s.Error = sess.Err.Error()
+	}
+	return s
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// toStatus maps the manager errors to grpc status codes.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, ceremony.ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ceremony.ErrKeyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ceremony.ErrSessionNotFound), errors.Is(err, party.ErrKeyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -3,16 +3,15 @@ package client
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
-	"time"
 
 	"github.com/bnb-chain/tss-lib/v2/tss"
-	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
+	"github.com/smiletrl/tss-lib-starter/pkg/config"
 	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/smiletrl/tss-lib-starter/pkg/constants"
 )
@@ -22,29 +21,66 @@ type Client interface {
 	// with party id
 	WithPartyID(pid *tss.PartyID)
 
-	// broadcast message to all nodes
-	BroadcastNodes(ctx context.Context, msgType constants.MessageType, msg tss.Message) error
+	// broadcast message to all nodes joining this session, the parties. Resharing messages go to their own
+	// destinations instead. It tries all nodes even if some fail, and returns a *DeliveryError naming them.
+	BroadcastNodes(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, msg tss.Message) error
 
-	// send message to one node
-	ToNode(ctx context.Context, pid string, msgType constants.MessageType, msg tss.Message) error
+	// send message to one node joining this session
+	ToNode(ctx context.Context, sessionID string, parties []string, pid string, msgType constants.MessageType, msg tss.Message) error
+
+	// broadcast the raw content to all nodes joining this session, i.e, the nonce starting one run of the ceremony
+	Announce(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, content []byte) error
+
+	// tell all nodes to abort the session, blaming the culprits
+	Abort(ctx context.Context, sessionID string, msgType constants.MessageType, culprits []string, reason string) error
 }
 
 type client struct {
-	// grpc host data, key is party unique id, value is an array with fixed length 2: host + port.
-	hosts map[string][2]string
+	// grpc server address of each party, key is party unique id, value is host:port
+	addresses map[string]string
+
+	// party unique ids of all parties
+	peers []string
+
+	// relay storing and forwarding all frames, instead of sending them to the parties directly. See `NewRelayClient`.
+	relay pb.RelayClient
 
-	// it holds all parties grpc client, key is party unique id
-	clients    map[string]pb.P2PClient
-	clientOnce *sync.Once
+	// dir to write all frames into, instead of sending them to the parties directly. See `NewFileClient`.
+	outbox string
+
+	// connections to all parties, key is party unique id. Connections are made on the first frame to each party.
+	conns   map[string]*peerConn
+	connsMu sync.Mutex
+
+	// why the last message to each party fails, key is party unique id
+	lastErrs map[string]error
 
 	// party unique id
 	pid *tss.PartyID
+
+	// transport credentials, mutual tls or insecure
+	creds credentials.TransportCredentials
+
+	// identity key signing all envelopes and encrypting point to point messages, neither if nil
+	identity *identity.Identity
+
+	// public identities of all parties, to encrypt messages to them
+	registry identity.Registry
 }
 
-func NewClient(hosts map[string][2]string) (Client, error) {
+func NewClient(cfg *config.Config, creds credentials.TransportCredentials, identity *identity.Identity, registry identity.Registry) (Client, error) {
+	addresses := make(map[string]string, len(cfg.Parties))
+	for _, p := range cfg.Parties {
+		addresses[p.ID] = p.Address
+	}
 	c := &client{
-		hosts:      hosts,
-		clientOnce: &sync.Once{},
+		addresses: addresses,
+		peers:     cfg.PartyIDs(),
+		conns:     make(map[string]*peerConn),
+		lastErrs:  make(map[string]error),
+		creds:     creds,
+		identity:  identity,
+		registry:  registry,
 	}
 
 	return c, nil
@@ -54,52 +90,7 @@ func (c *client) WithPartyID(pid *tss.PartyID) {
 	c.pid = pid
 }
 
-// lazy load because grpc server might not be running when client is initialized.
-func (c *client) grpc() map[string]pb.P2PClient {
-	c.clientOnce.Do(func() {
-		tempClients := make(map[string]pb.P2PClient, len(c.hosts))
-		for i, host := range c.hosts {
-			conn, err := c.newConnectionClient(host[0], host[1])
-			if err != nil {
-				panic("error new grpc client:" + err.Error())
-			}
-			tempClients[i] = conn
-		}
-		c.clients = tempClients
-	})
-	return c.clients
-}
-
-func (c *client) newConnectionClient(host, port string) (client pb.P2PClient, err error) {
-	var address = fmt.Sprintf("%s:%s", host, port)
-
-	var kacp = keepalive.ClientParameters{
-		Time:                10 * time.Second, // send pings every 10 seconds if there is no activity
-		Timeout:             time.Second,      // wait 1 second for ping ack before considering the connection dead
-		PermitWithoutStream: true,             // send pings even without active streams
-	}
-
-	// only allow maximum 1 second connection.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	conn, err := grpc.DialContext(ctx, address,
-		grpc.WithInsecure(),
-		grpc.WithBlock(),
-		grpc.WithKeepaliveParams(kacp),
-		grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
-			grpc_opentracing.StreamClientInterceptor(),
-		)),
-		grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
-			grpc_opentracing.UnaryClientInterceptor(),
-		)),
-	)
-	if err != nil {
-		return nil, err
-	}
-	return pb.NewP2PClient(conn), nil
-}
-
-func (c *client) BroadcastNodes(ctx context.Context, msgType constants.MessageType, msg tss.Message) error {
+func (c *client) BroadcastNodes(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, msg tss.Message) error {
 	// broadcast message to all party nodes
 	msgID := msg.GetFrom().GetId()
 	bz, _, err := msg.WireBytes()
@@ -107,56 +98,141 @@ func (c *client) BroadcastNodes(ctx context.Context, msgType constants.MessageTy
 		return fmt.Errorf("error getting wire bytes: %w", err)
 	}
 
-	for id, g := range c.grpc() {
-		// should not send to itself
-		if id == msgID {
-			continue
-		}
+	// resharing messages go to their own destinations, within the old or the new committee
+	if msgType == constants.MessageTypeResharing {
+		return c.toCommittee(ctx, sessionID, msg, bz)
+	}
 
-		// for signing, if this party is not selected in this round, continue
-		if msgType == constants.MessageTypeSigning {
-			if _, ok := constants.SelectedParties[id]; !ok {
-				continue
-			}
-		}
+	return c.broadcast(ctx, sessionID, parties, msgType, msgID, bz)
+}
 
-		if _, err := g.OnReceiveMessage(ctx, &pb.Message{
-			Type:        string(msgType),
-			Content:     bz,
-			IsBroadcast: true,
-			FromPid:     msgID,
-		}); err != nil {
-			return err
+func (c *client) Announce(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, content []byte) error {
+	return c.broadcast(ctx, sessionID, parties, msgType, c.pid.GetId(), content)
+}
+
+// broadcast sends the content to all parties of this session except the sender.
+func (c *client) broadcast(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, from string, content []byte) error {
+	// should not send to itself
+	pids := make([]string, 0, len(parties))
+	for _, id := range parties {
+		if id != from {
+			pids = append(pids, id)
 		}
 	}
 
-	return nil
+	messageID := newMessageID()
+	return c.fanOut(ctx, pids, messageID, func(string) *pb.Frame {
+		return message(&pb.Message{
+			Type:        string(msgType),
+			Content:     content,
+			IsBroadcast: true,
+			FromPid:     from,
+			SessionId:   sessionID,
+			Parties:     parties,
+			MessageId:   messageID,
+		})
+	})
 }
 
-func (c *client) ToNode(ctx context.Context, pid string, msgType constants.MessageType, msg tss.Message) error {
-	g, ok := c.grpc()[pid]
-	if !ok {
-		return fmt.Errorf("unexpected party unique id: %s", pid)
-	}
-
-	// for signing, if this party is not selected in this round, continue
-	if msgType == constants.MessageTypeSigning {
-		if _, ok := constants.SelectedParties[pid]; !ok {
-			return fmt.Errorf("unexpected to node request: %s", pid)
-		}
+func (c *client) ToNode(ctx context.Context, sessionID string, parties []string, pid string, msgType constants.MessageType, msg tss.Message) error {
+	// the target node must join this session, i.e, be one of the signers
+	if !slices.Contains(parties, pid) {
+		return fmt.Errorf("unexpected to node request: %s", pid)
 	}
 
 	bz, _, err := msg.WireBytes()
 	if err != nil {
 		return fmt.Errorf("error getting wire bytes: %w", err)
 	}
-	if _, err := g.OnReceiveMessage(ctx, &pb.Message{
+	messageID := newMessageID()
+	failed := &DeliveryError{}
+	if err := c.send(ctx, pid, message(&pb.Message{
 		Type:        string(msgType),
 		Content:     bz,
 		IsBroadcast: false,
 		FromPid:     c.pid.GetId(),
-	}); err != nil {
-		return fmt.Errorf("party with unique id %s fails receiving message: %w", pid, err)
+		SessionId:   sessionID,
+		Parties:     parties,
+		MessageId:   messageID,
+	})); err != nil {
+		failed.add(pid, messageID, err)
+	}
+	return failed.err()
+}
+
+// toCommittee sends one resharing message to all its destinations. One node may join both the old and the new
+// committee, so it only receives the message once, including when it's the node sending this message.
+func (c *client) toCommittee(ctx context.Context, sessionID string, msg tss.Message, bz []byte) error {
+	pids := make([]string, 0, len(msg.GetTo()))
+	for _, to := range msg.GetTo() {
+		if !slices.Contains(pids, to.GetId()) {
+			pids = append(pids, to.GetId())
+		}
+	}
+
+	messageID := newMessageID()
+	return c.fanOut(ctx, pids, messageID, func(string) *pb.Frame {
+		return message(&pb.Message{
+			Type:                  string(constants.MessageTypeResharing),
+			Content:               bz,
+			IsBroadcast:           msg.IsBroadcast(),
+			FromPid:               msg.GetFrom().GetId(),
+			SessionId:             sessionID,
+			FromKey:               msg.GetFrom().GetKey(),
+			ToOldCommittee:        msg.IsToOldCommittee(),
+			ToOldAndNewCommittees: msg.IsToOldAndNewCommittees(),
+			MessageId:             messageID,
+		})
+	})
+}
+
+func (c *client) Abort(ctx context.Context, sessionID string, msgType constants.MessageType, culprits []string, reason string) error {
+	// nodes not joining this session just ignore it, so send to all nodes except itself
+	pids := make([]string, 0, len(c.peers))
+	for _, id := range c.peers {
+		if id != c.pid.GetId() {
+			pids = append(pids, id)
+		}
+	}
+	return c.fanOut(ctx, pids, "", func(string) *pb.Frame {
+		return &pb.Frame{Body: &pb.Frame_Abort{Abort: &pb.Abort{
+			SessionId: sessionID,
+			FromPid:   c.pid.GetId(),
+			Type:      string(msgType),
+			Culprits:  culprits,
+			Reason:    reason,
+		}}}
+	})
+}
+
+func message(msg *pb.Message) *pb.Frame {
+	return &pb.Frame{Body: &pb.Frame_Message{Message: msg}}
+}
+
+// seal addresses the envelope to the party, encrypts point to point messages to it, and signs the envelope with
+// this node's identity key.
+func (c *client) seal(pid string, frame *pb.Frame) error {
+	switch body := frame.GetBody().(type) {
+	case *pb.Frame_Message:
+		msg := body.Message
+		msg.ToPid = pid
+		if c.identity == nil {
+			return nil
+		}
+		// point to point messages carry secret shares, so only the receiver may read them, even through a proxy
+		if !msg.GetIsBroadcast() && !msg.GetEncrypted() {
+			content, err := c.identity.Seal(c.registry, pid, msg.GetContent(), msg.AdditionalData())
+			if err != nil {
+				return fmt.Errorf("error encrypting message to party %s: %w", pid, err)
+			}
+			msg.Content, msg.Encrypted = content, true
+		}
+		msg.Signature = c.identity.Sign(msg.SigningBytes())
+	case *pb.Frame_Abort:
+		body.Abort.ToPid = pid
+		if c.identity != nil {
+			body.Abort.Signature = c.identity.Sign(body.Abort.SigningBytes())
+		}
 	}
 	return nil
 }
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/keepalive"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+)
+
+// StatusReporter reports the connectivity of the client to other parties.
+type StatusReporter interface {
+	Status() []PeerStatus
+}
+
+// PeerStatus is the connectivity to one party.
+type PeerStatus struct {
+	PartyID string
+
+	// grpc connectivity state of the direct connection, i.e, `READY` or `TRANSIENT_FAILURE`, or `IDLE` if nothing has
+	// been sent to this party yet. Empty if this party isn't connected directly, i.e, through relay.
+	State string
+
+	// why the last message to this party fails after all retries, empty once one message goes through again
+	LastError string
+}
+
+// peerConn is the connection to one party. It's made on the first frame to this party, and grpc re-establishes it
+// with backoff whenever it breaks, so one party being down never affects the others.
+type peerConn struct {
+	conn   *grpc.ClientConn
+	client pb.P2PClient
+
+	// long-lived stream to this party, opened on the first frame, and reopened once it breaks
+	mu sync.Mutex
+	st *stream
+}
+
+// conn returns the connection to the party, making it if there is none yet. Making it never blocks, grpc connects in
+// the background.
+func (c *client) conn(pid string) (*peerConn, error) {
+	address, ok := c.addresses[pid]
+	if !ok {
+		return nil, &rejectedError{err: fmt.Errorf("unexpected party unique id: %s", pid)}
+	}
+
+	c.connsMu.Lock()
+	defer c.connsMu.Unlock()
+
+	if pc, ok := c.conns[pid]; ok {
+		return pc, nil
+	}
+	conn, err := c.newConnection(pid, address)
+	if err != nil {
+		return nil, &rejectedError{err: fmt.Errorf("error connecting party %s: %w", pid, err)}
+	}
+	pc := &peerConn{conn: conn, client: pb.NewP2PClient(conn)}
+	c.conns[pid] = pc
+	return pc, nil
+}
+
+func (c *client) newConnection(id, address string) (*grpc.ClientConn, error) {
+	var kacp = keepalive.ClientParameters{
+		Time:                10 * time.Second, // send pings every 10 seconds if there is no activity
+		Timeout:             time.Second,      // wait 1 second for ping ack before considering the connection dead
+		PermitWithoutStream: true,             // send pings even without active streams
+	}
+
+	// reconnect with backoff up to 10 seconds, so a party coming back joins the next message soon
+	connectBackoff := backoff.DefaultConfig
+	connectBackoff.MaxDelay = 10 * time.Second
+
+	return grpc.NewClient(address,
+		grpc.WithTransportCredentials(c.creds),
+		// with tls, the server certificate must be issued for the target party unique id
+		grpc.WithAuthority(id),
+		grpc.WithKeepaliveParams(kacp),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: connectBackoff, MinConnectTimeout: 5 * time.Second}),
+		grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
+			grpc_opentracing.StreamClientInterceptor(),
+		)),
+		grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
+			grpc_opentracing.UnaryClientInterceptor(),
+		)),
+	)
+}
+
+// stream returns the stream to the party. A new stream is opened if there is none yet, or the last one has broken.
+func (c *client) stream(pid string) (*stream, error) {
+	pc, err := c.conn(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.st != nil && !pc.st.closed() {
+		return pc.st, nil
+	}
+	st, err := newStream(pc.client)
+	if err != nil {
+		return nil, fmt.Errorf("error opening stream to party %s: %w", pid, err)
+	}
+	pc.st = st
+	return st, nil
+}
+
+// record keeps the result of the last message to the party, for `Status`.
+func (c *client) record(pid string, err error) {
+	c.connsMu.Lock()
+	defer c.connsMu.Unlock()
+
+	if err == nil {
+		delete(c.lastErrs, pid)
+		return
+	}
+	c.lastErrs[pid] = err
+}
+
+func (c *client) Status() []PeerStatus {
+	c.connsMu.Lock()
+	defer c.connsMu.Unlock()
+
+	statuses := make([]PeerStatus, 0, len(c.peers))
+	for _, id := range c.peers {
+		if c.pid != nil && id == c.pid.GetId() {
+			continue
+		}
+		status := PeerStatus{PartyID: id}
+		if pc, ok := c.conns[id]; ok {
+			status.State = pc.conn.GetState().String()
+		} else if c.relay == nil && c.outbox == "" {
+			status.State = "IDLE"
+		}
+		if err, ok := c.lastErrs[id]; ok {
+			status.LastError = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	slices.SortFunc(statuses, func(a, b PeerStatus) int {
+		if a.PartyID < b.PartyID {
+			return -1
+		}
+		if a.PartyID > b.PartyID {
+			return 1
+		}
+		return 0
+	})
+	return statuses
+}
+
+// sendStream sends the frame over the stream to the party, and waits for its ack.
+func (c *client) sendStream(ctx context.Context, pid string, frame *pb.Frame) error {
+	st, err := c.stream(pid)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ackTimeout)
+	defer cancel()
+	return st.send(ctx, frame)
+}
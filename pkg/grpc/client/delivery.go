@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+)
+
+const (
+	// attempts to deliver one message to one party, including the first one
+	maxAttempts = 8
+
+	// backoff between attempts, doubled after each attempt
+	retryBackoff    = 200 * time.Millisecond
+	maxRetryBackoff = 3 * time.Second
+
+	// wait for the ack of each attempt
+	ackTimeout = 10 * time.Second
+
+	// deadline delivering one message to one party, including all retries
+	peerTimeout = 30 * time.Second
+
+	// bound of the parties receiving one message at the same time
+	maxConcurrentSends = 16
+)
+
+// DeliveryError tells which parties have not received which messages, after all retries.
+type DeliveryError struct {
+	Failures []DeliveryFailure
+}
+
+// DeliveryFailure is one message not received by one party.
+type DeliveryFailure struct {
+	PartyID   string
+	MessageID string
+	Err       error
+}
+
+func (e *DeliveryError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		failures[i] = fmt.Sprintf("party %s fails receiving message %s: %v", f.PartyID, f.MessageID, f.Err)
+	}
+	return strings.Join(failures, "; ")
+}
+
+// Parties returns the party unique ids not receiving the messages.
+func (e *DeliveryError) Parties() []string {
+	parties := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		parties = append(parties, f.PartyID)
+	}
+	return parties
+}
+
+func (e *DeliveryError) add(pid, messageID string, err error) {
+	e.Failures = append(e.Failures, DeliveryFailure{
+		PartyID:   pid,
+		MessageID: messageID,
+		Err:       err,
+	})
+}
+
+// err returns the delivery error, or nil if all messages are received.
+func (e *DeliveryError) err() error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+// rejectedError is returned by the receiving party itself, i.e, an invalid signature. Retrying it won't help.
+type rejectedError struct {
+	err error
+}
+
+func (e *rejectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rejectedError) Unwrap() error {
+	return e.err
+}
+
+func newMessageID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("error generating message id:" + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// fanOut sends one message to all the parties in parallel, so one slow party doesn't delay the others. Each party
+// gets its own frame from newFrame, as the frame is sealed to it.
+func (c *client) fanOut(ctx context.Context, pids []string, messageID string, newFrame func(pid string) *pb.Frame) error {
+	errs := make([]error, len(pids))
+	sem := make(chan struct{}, maxConcurrentSends)
+	var wg sync.WaitGroup
+	for i, pid := range pids {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = c.send(ctx, pid, newFrame(pid))
+		}()
+	}
+	wg.Wait()
+
+	failed := &DeliveryError{}
+	for i, err := range errs {
+		if err != nil {
+			failed.add(pids[i], messageID, err)
+		}
+	}
+	return failed.err()
+}
+
+// send sends the frame to the party and waits for its ack, retrying with backoff until the party acks it, rejects
+// it, all attempts fail, or the peer deadline passes. A retried message keeps its id, so the party delivers it only once.
+func (c *client) send(ctx context.Context, pid string, frame *pb.Frame) (err error) {
+	defer func() {
+		c.record(pid, err)
+	}()
+
+	if err := c.seal(pid, frame); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, peerTimeout)
+	defer cancel()
+
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.sendOnce(ctx, pid, frame)
+		if err == nil {
+			return nil
+		}
+		var rejected *rejectedError
+		if attempt == maxAttempts || errors.As(err, &rejected) || ctx.Err() != nil {
+			return err
+		}
+
+		log.Printf("error sending frame to party %s, attempt %d, retry in %v: %v", pid, attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// sendOnce sends the frame to the party once, directly, through the relay or into the outbox.
+func (c *client) sendOnce(ctx context.Context, pid string, frame *pb.Frame) error {
+	if c.relay != nil {
+		return c.sendRelay(ctx, frame)
+	}
+	if c.outbox != "" {
+		return c.writeFrame(pid, frame)
+	}
+	return c.sendStream(ctx, pid, frame)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
+)
+
+// FrameFileExt is the extension of the files holding one frame each.
+const FrameFileExt = ".frame"
+
+// NewFileClient returns the client writing all frames into files within the outbox dir, one sub dir per receiving
+// party, i.e, `outbox/p2`. It's for air-gapped parties, the files are carried over to the receivers by hand, i.e, by
+// USB. Frames for the local party are read from files too, see `server.Inbox`.
+// Identities are optional, but without them anyone touching the files may read or forge messages.
+func NewFileClient(outbox string, parties []string, identity *identity.Identity, registry identity.Registry) (Client, error) {
+	for _, id := range parties {
+		if err := os.MkdirAll(filepath.Join(outbox, id), 0700); err != nil {
+			return nil, fmt.Errorf("error creating outbox dir: %w", err)
+		}
+	}
+	c := &client{
+		peers:    parties,
+		lastErrs: make(map[string]error),
+		outbox:   outbox,
+		identity: identity,
+		registry: registry,
+	}
+	return c, nil
+}
+
+// writeFrame writes the frame into the outbox of the party. The file is renamed into place once it's complete, so
+// it's never carried over half written.
+func (c *client) writeFrame(pid string, frame *pb.Frame) error {
+	bz, err := proto.Marshal(frame)
+	if err != nil {
+		return &rejectedError{err: fmt.Errorf("error marshalling frame: %w", err)}
+	}
+
+	// file names sort in the order the frames are written
+	id := newMessageID()
+	if msg := frame.GetMessage(); msg != nil {
+		id = msg.GetMessageId()
+	}
+	name := fmt.Sprintf("%d-%s-%s%s", time.Now().UnixNano(), frame.FromPid(), id, FrameFileExt)
+	path := filepath.Join(c.outbox, pid, name)
+
+	if err := os.WriteFile(path+".tmp", bz, 0600); err != nil {
+		return fmt.Errorf("error writing frame file: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("error writing frame file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+)
+
+// Handshaker exchanges the announcements and the roster signatures with other parties directly, before any ceremony.
+// Only the client made by `NewClient` implements it, parties behind relay or offline can't be reached by a call.
+type Handshaker interface {
+	// announce this party to the party, and get its announcement back
+	Handshake(ctx context.Context, pid string, hello *pb.Hello) (*pb.Hello, error)
+
+	// ask the party to sign the roster
+	SignRoster(ctx context.Context, pid string, roster *pb.Roster) (*pb.RosterSignature, error)
+}
+
+func (c *client) Handshake(ctx context.Context, pid string, hello *pb.Hello) (*pb.Hello, error) {
+	pc, err := c.conn(pid)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ackTimeout)
+	defer cancel()
+	return pc.client.Handshake(ctx, hello)
+}
+
+func (c *client) SignRoster(ctx context.Context, pid string, roster *pb.Roster) (*pb.RosterSignature, error) {
+	pc, err := c.conn(pid)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ackTimeout)
+	defer cancel()
+	return pc.client.SignRoster(ctx, roster)
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
+)
+
+// NewRelayClient returns the client sending all frames through the relay, for parties which can't dial each other,
+// i.e, behind NAT. Frames for the local party are received from the relay too, see `server.ServeRelay`.
+// Identities are required, so the relay can neither read point to point messages nor forge any message.
+func NewRelayClient(relay pb.RelayClient, parties []string, identity *identity.Identity, registry identity.Registry) (Client, error) {
+	if identity == nil || registry == nil {
+		return nil, errors.New("identity and registry are required with relay")
+	}
+	c := &client{
+		peers:    parties,
+		lastErrs: make(map[string]error),
+		relay:    relay,
+		identity: identity,
+		registry: registry,
+	}
+	return c, nil
+}
+
+// sendRelay stores the frame within the relay, and waits for the relay to ack it. The relay delivers it to the party
+// later, once the party connects.
+func (c *client) sendRelay(ctx context.Context, frame *pb.Frame) error {
+	ctx, cancel := context.WithTimeout(ctx, ackTimeout)
+	defer cancel()
+
+	ack, err := c.relay.Send(ctx, frame)
+	if err != nil {
+		return fmt.Errorf("error sending frame to relay: %w", err)
+	}
+	if ack.GetError() != "" {
+		return &rejectedError{err: errors.New(ack.GetError())}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+)
+
+// stream is the long-lived bidi stream to one party. Frames of all sessions to this party are multiplexed over it,
+// so they arrive in the same order they are sent.
+type stream struct {
+	s      pb.P2P_StreamClient
+	cancel context.CancelFunc
+
+	// guards sending, so frames are sent in seq order
+	sendMu sync.Mutex
+	seq    uint64
+
+	// waiting senders, key is frame seq
+	mu      sync.Mutex
+	pending map[uint64]chan error
+
+	// closed once the stream breaks, with the reason within err
+	done chan struct{}
+	err  error
+}
+
+func newStream(g pb.P2PClient) (*stream, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := g.Stream(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	st := &stream{
+		s:       s,
+		cancel:  cancel,
+		pending: make(map[uint64]chan error),
+		done:    make(chan struct{}),
+	}
+	go st.receive()
+	return st, nil
+}
+
+// send sends the frame, and waits until the party acks it.
+func (st *stream) send(ctx context.Context, frame *pb.Frame) error {
+	ack := make(chan error, 1)
+
+	st.sendMu.Lock()
+	st.seq++
+	frame.Seq = st.seq
+	st.mu.Lock()
+	st.pending[frame.Seq] = ack
+	st.mu.Unlock()
+	err := st.s.Send(frame)
+	st.sendMu.Unlock()
+
+	if err != nil {
+		st.mu.Lock()
+		delete(st.pending, frame.Seq)
+		st.mu.Unlock()
+		st.close(err)
+		return fmt.Errorf("error sending frame: %w", err)
+	}
+
+	select {
+	case err := <-ack:
+		return err
+	case <-st.done:
+		return fmt.Errorf("stream closed: %w", st.err)
+	case <-ctx.Done():
+		// the ack might still come, and is dropped then
+		st.mu.Lock()
+		delete(st.pending, frame.Seq)
+		st.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// receive hands the acks over to their waiting senders, until the stream breaks.
+func (st *stream) receive() {
+	for {
+		ack, err := st.s.Recv()
+		if err != nil {
+			st.close(err)
+			return
+		}
+
+		st.mu.Lock()
+		ch, ok := st.pending[ack.GetSeq()]
+		delete(st.pending, ack.GetSeq())
+		st.mu.Unlock()
+		if !ok {
+			continue
+		}
+		switch {
+		case ack.GetRetryable():
+			// the party is busy for now, so the frame is sent again after backoff
+			ch <- errors.New(ack.GetError())
+		case ack.GetError() != "":
+			ch <- &rejectedError{err: errors.New(ack.GetError())}
+		default:
+			ch <- nil
+		}
+	}
+}
+
+// close breaks the stream, and fails all waiting senders. Only the first reason is kept.
+func (st *stream) close(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	select {
+	case <-st.done:
+		return
+	default:
+	}
+	st.err = err
+	close(st.done)
+	st.cancel()
+}
+
+func (st *stream) closed() bool {
+	select {
+	case <-st.done:
+		return true
+	default:
+		return false
+	}
+}
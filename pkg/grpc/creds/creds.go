@@ -0,0 +1,116 @@
+package creds
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+)
+
+// Config is the TLS config of one node. Each party holds its own certificate signed by the shared CA, with the party
+// unique id as both common name and DNS name, i.e, `p1`.
+// Leaving all files empty disables TLS, which is fine for local tests only.
+type Config struct {
+	// this node's certificate and private key
+	CertFile, KeyFile string
+
+	// CA certificate signing all parties' certificates
+	CAFile string
+}
+
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != ""
+}
+
+func (c Config) load() (tls.Certificate, *x509.CertPool, error) {
+	if c.CertFile == "" || c.KeyFile == "" || c.CAFile == "" {
+		return tls.Certificate{}, nil, errors.New("cert, key and ca files are all required for tls")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("error loading tls key pair: %w", err)
+	}
+	ca, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("error reading tls ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return tls.Certificate{}, nil, fmt.Errorf("no certificate found within tls ca file: %s", c.CAFile)
+	}
+	return cert, pool, nil
+}
+
+// ServerCredentials requires the client certificate signed by the CA, for one party of the roster.
+func ServerCredentials(cfg Config, parties []string) (credentials.TransportCredentials, error) {
+	if !cfg.Enabled() {
+		return insecure.NewCredentials(), nil
+	}
+	tlsConfig, err := ServerTLSConfig(cfg, parties)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// ServerTLSConfig is the tls config behind `ServerCredentials`, for servers other than grpc, i.e, http. It requires
+// the client certificate signed by the CA, for one of the given names.
+func ServerTLSConfig(cfg Config, names []string) (*tls.Config, error) {
+	cert, pool, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+		VerifyPeerCertificate: func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+			if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+				return errors.New("no verified client certificate")
+			}
+			id := verifiedChains[0][0].Subject.CommonName
+			if !slices.Contains(names, id) {
+				return fmt.Errorf("unexpected client certificate for party: %q", id)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// ClientCredentials presents this node's certificate, and checks the server certificate against the CA. The server
+// name is the authority of each connection, which is the target party unique id, see `client.newConnection`.
+func ClientCredentials(cfg Config) (credentials.TransportCredentials, error) {
+	if !cfg.Enabled() {
+		return insecure.NewCredentials(), nil
+	}
+	cert, pool, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS13,
+	}), nil
+}
+
+// PartyID returns the party unique id of the client certificate, or false if this request doesn't come over tls.
+func PartyID(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName, true
+}
@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"encoding/binary"
+)
+
+// domain separation of the signed envelopes, so a signature over one kind of envelope is never valid for another
+const (
+	messageDomain = "tss-lib-starter/message/v1"
+	abortDomain   = "tss-lib-starter/abort/v1"
+	helloDomain   = "tss-lib-starter/hello/v1"
+	rosterDomain  = "tss-lib-starter/roster/v1"
+)
+
+// SigningBytes returns the bytes the sender signs with its identity key, covering every field except the signature.
+func (m *Message) SigningBytes() []byte {
+	b := appendBytes(nil, []byte(messageDomain))
+	b = appendBytes(b, []byte(m.GetSessionId()))
+	b = appendBytes(b, []byte(m.GetType()))
+	b = appendBytes(b, []byte(m.GetFromPid()))
+	b = appendBytes(b, []byte(m.GetToPid()))
+	b = appendBytes(b, m.GetContent())
+	b = appendBool(b, m.GetIsBroadcast())
+	b = appendBytes(b, m.GetFromKey())
+	b = appendBool(b, m.GetToOldCommittee())
+	b = appendBool(b, m.GetToOldAndNewCommittees())
+	b = appendStrings(b, m.GetParties())
+	b = appendBool(b, m.GetEncrypted())
+	return appendBytes(b, []byte(m.GetMessageId()))
+}
+
+// AdditionalData returns the bytes authenticated along with the encrypted content, binding the content to this
+// session, sender and receiver.
+func (m *Message) AdditionalData() []byte {
+	b := appendBytes(nil, []byte(messageDomain))
+	b = appendBytes(b, []byte(m.GetSessionId()))
+	b = appendBytes(b, []byte(m.GetType()))
+	b = appendBytes(b, []byte(m.GetFromPid()))
+	return appendBytes(b, []byte(m.GetToPid()))
+}
+
+// SigningBytes returns the bytes the sender signs with its identity key, covering every field except the signature.
+func (a *Abort) SigningBytes() []byte {
+	b := appendBytes(nil, []byte(abortDomain))
+	b = appendBytes(b, []byte(a.GetSessionId()))
+	b = appendBytes(b, []byte(a.GetType()))
+	b = appendBytes(b, []byte(a.GetFromPid()))
+	b = appendBytes(b, []byte(a.GetToPid()))
+	b = appendStrings(b, a.GetCulprits())
+	return appendBytes(b, []byte(a.GetReason()))
+}
+
+// SigningBytes returns the bytes the party signs with its identity key, covering every field except the signature.
+func (h *Hello) SigningBytes() []byte {
+	b := appendBytes(nil, []byte(helloDomain))
+	b = appendBytes(b, []byte(h.GetPartyId()))
+	b = appendBytes(b, []byte(h.GetMoniker()))
+	b = appendBytes(b, []byte(h.GetKey()))
+	b = appendBytes(b, []byte(h.GetAddress()))
+	b = appendBytes(b, h.GetSignKey())
+	return appendBytes(b, h.GetBoxKey())
+}
+
+// SigningBytes returns the bytes each party signs to agree on the roster, covering the threshold and all
+// announcements except the signatures.
+func (r *Roster) SigningBytes() []byte {
+	b := appendBytes(nil, []byte(rosterDomain))
+	b = binary.BigEndian.AppendUint64(b, uint64(r.GetThreshold()))
+	b = binary.BigEndian.AppendUint64(b, uint64(len(r.GetParties())))
+	for _, h := range r.GetParties() {
+		b = appendBytes(b, h.SigningBytes())
+	}
+	return b
+}
+
+// appendBytes appends the length prefixed bytes, so no two different envelopes share the same signing bytes.
+func appendBytes(b, v []byte) []byte {
+	b = binary.BigEndian.AppendUint64(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func appendStrings(b []byte, v []string) []byte {
+	b = binary.BigEndian.AppendUint64(b, uint64(len(v)))
+	for _, s := range v {
+		b = appendBytes(b, []byte(s))
+	}
+	return b
+}
+
+// FromPid returns which party id the body of this frame comes from.
+func (f *Frame) FromPid() string {
+	switch body := f.GetBody().(type) {
+	case *Frame_Message:
+		return body.Message.GetFromPid()
+	case *Frame_Abort:
+		return body.Abort.GetFromPid()
+	}
+	return ""
+}
+
+// ToPid returns which party id the body of this frame goes to.
+func (f *Frame) ToPid() string {
+	switch body := f.GetBody().(type) {
+	case *Frame_Message:
+		return body.Message.GetToPid()
+	case *Frame_Abort:
+		return body.Abort.GetToPid()
+	}
+	return ""
+}
+
+// SessionId returns which ceremony session the body of this frame belongs to.
+func (f *Frame) SessionId() string {
+	switch body := f.GetBody().(type) {
+	case *Frame_Message:
+		return body.Message.GetSessionId()
+	case *Frame_Abort:
+		return body.Abort.GetSessionId()
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package grpc_test
+
+import (
+	"testing"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestVerifyMessage(t *testing.T) {
+	r := identity.Registry{}
+	identities := make(map[string]*identity.Identity)
+	for _, id := range []string{"p1", "p2"} {
+		i, err := identity.GenerateIdentity(id)
+		if err != nil {
+			t.Fatalf("error generating identity: %v", err)
+		}
+		identities[id] = i
+		r[id] = i.Entry()
+	}
+	// p3 holds an identity key, which is not registered
+	unregistered, err := identity.GenerateIdentity("p3")
+	if err != nil {
+		t.Fatalf("error generating identity: %v", err)
+	}
+
+	msg := &pb.Message{
+		SessionId: "session",
+		Type:      "keygen",
+		FromPid:   "p1",
+		ToPid:     "p2",
+		Content:   []byte("round 1 share"),
+		Parties:   []string{"p1", "p2"},
+		MessageId: "p1/1",
+	}
+	msg.Signature = identities["p1"].Sign(msg.SigningBytes())
+	if err := r.Verify(msg.GetFromPid(), msg.SigningBytes(), msg.GetSignature()); err != nil {
+		t.Fatalf("error verifying message: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *pb.Message)
+	}{
+		{name: "modified session id", modify: func(m *pb.Message) { m.SessionId = "other session" }},
+		{name: "modified type", modify: func(m *pb.Message) { m.Type = "signing" }},
+		{name: "modified sender", modify: func(m *pb.Message) { m.FromPid = "p2" }},
+		{name: "modified receiver", modify: func(m *pb.Message) { m.ToPid = "p1" }},
+		{name: "modified content", modify: func(m *pb.Message) { m.Content = []byte("round 2 share") }},
+		{name: "modified parties", modify: func(m *pb.Message) { m.Parties = []string{"p1", "p2", "p3"} }},
+		{name: "unregistered key", modify: func(m *pb.Message) {
+			m.FromPid = "p3"
+			m.Signature = unregistered.Sign(m.SigningBytes())
+		}},
+		{name: "signed by another registered key", modify: func(m *pb.Message) {
+			m.Signature = identities["p2"].Sign(m.SigningBytes())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := proto.Clone(msg).(*pb.Message)
+			tt.modify(m)
+			if err := r.Verify(m.GetFromPid(), m.SigningBytes(), m.GetSignature()); err == nil {
+				t.Fatal("verified, want error")
+			}
+		})
+	}
+}
+
+func TestVerifyAbort(t *testing.T) {
+	i, err := identity.GenerateIdentity("p1")
+	if err != nil {
+		t.Fatalf("error generating identity: %v", err)
+	}
+	r := identity.Registry{"p1": i.Entry()}
+
+	abort := &pb.Abort{SessionId: "session", Type: "keygen", FromPid: "p1", ToPid: "p2", Reason: "timeout"}
+	abort.Signature = i.Sign(abort.SigningBytes())
+	if err := r.Verify(abort.GetFromPid(), abort.SigningBytes(), abort.GetSignature()); err != nil {
+		t.Fatalf("error verifying abort: %v", err)
+	}
+
+	// a message signature never verifies as an abort with the same fields
+	msg := &pb.Message{SessionId: "session", Type: "keygen", FromPid: "p1", ToPid: "p2"}
+	abort.Signature = i.Sign(msg.SigningBytes())
+	if err := r.Verify(abort.GetFromPid(), abort.SigningBytes(), abort.GetSignature()); err == nil {
+		t.Fatal("verified, want error")
+	}
+
+	abort.Signature = i.Sign(abort.SigningBytes())
+	abort.Culprits = []string{"p3"}
+	if err := r.Verify(abort.GetFromPid(), abort.SigningBytes(), abort.GetSignature()); err == nil {
+		t.Fatal("verified, want error")
+	}
+}
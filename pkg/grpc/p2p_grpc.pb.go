@@ -21,6 +21,15 @@ const _ = grpc.SupportPackageIsVersion7
 type P2PClient interface {
 	// on receive message
 	OnReceiveMessage(ctx context.Context, in *Message, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// on receive abort of one ceremony session
+	OnReceiveAbort(ctx context.Context, in *Abort, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// long-lived stream from one party to another. Messages and aborts of all sessions are sent over it in order, and
+	// each of them is acked with the same seq.
+	Stream(ctx context.Context, opts ...grpc.CallOption) (P2P_StreamClient, error)
+	// announce this party to another one on start, and get its announcement back
+	Handshake(ctx context.Context, in *Hello, opts ...grpc.CallOption) (*Hello, error)
+	// ask another party to sign the roster, once it has built the same roster from all announcements
+	SignRoster(ctx context.Context, in *Roster, opts ...grpc.CallOption) (*RosterSignature, error)
 }
 
 type p2PClient struct {
@@ -40,12 +49,79 @@ func (c *p2PClient) OnReceiveMessage(ctx context.Context, in *Message, opts ...g
 	return out, nil
 }
 
+func (c *p2PClient) OnReceiveAbort(ctx context.Context, in *Abort, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.P2P/OnReceiveAbort", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *p2PClient) Stream(ctx context.Context, opts ...grpc.CallOption) (P2P_StreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &P2P_ServiceDesc.Streams[0], "/proto.P2P/Stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &p2PStreamClient{stream}
+	return x, nil
+}
+
+type P2P_StreamClient interface {
+	Send(*Frame) error
+	Recv() (*Ack, error)
+	grpc.ClientStream
+}
+
+type p2PStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *p2PStreamClient) Send(m *Frame) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *p2PStreamClient) Recv() (*Ack, error) {
+	m := new(Ack)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *p2PClient) Handshake(ctx context.Context, in *Hello, opts ...grpc.CallOption) (*Hello, error) {
+	out := new(Hello)
+	err := c.cc.Invoke(ctx, "/proto.P2P/Handshake", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *p2PClient) SignRoster(ctx context.Context, in *Roster, opts ...grpc.CallOption) (*RosterSignature, error) {
+	out := new(RosterSignature)
+	err := c.cc.Invoke(ctx, "/proto.P2P/SignRoster", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // P2PServer is the server API for P2P service.
 // All implementations must embed UnimplementedP2PServer
 // for forward compatibility
 type P2PServer interface {
 	// on receive message
 	OnReceiveMessage(context.Context, *Message) (*emptypb.Empty, error)
+	// on receive abort of one ceremony session
+	OnReceiveAbort(context.Context, *Abort) (*emptypb.Empty, error)
+	// long-lived stream from one party to another. Messages and aborts of all sessions are sent over it in order, and
+	// each of them is acked with the same seq.
+	Stream(P2P_StreamServer) error
+	// announce this party to another one on start, and get its announcement back
+	Handshake(context.Context, *Hello) (*Hello, error)
+	// ask another party to sign the roster, once it has built the same roster from all announcements
+	SignRoster(context.Context, *Roster) (*RosterSignature, error)
 	mustEmbedUnimplementedP2PServer()
 }
 
@@ -56,6 +132,18 @@ type UnimplementedP2PServer struct {
 func (UnimplementedP2PServer) OnReceiveMessage(context.Context, *Message) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method OnReceiveMessage not implemented")
 }
+func (UnimplementedP2PServer) OnReceiveAbort(context.Context, *Abort) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OnReceiveAbort not implemented")
+}
+func (UnimplementedP2PServer) Stream(P2P_StreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedP2PServer) Handshake(context.Context, *Hello) (*Hello, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
+}
+func (UnimplementedP2PServer) SignRoster(context.Context, *Roster) (*RosterSignature, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignRoster not implemented")
+}
 func (UnimplementedP2PServer) mustEmbedUnimplementedP2PServer() {}
 
 // UnsafeP2PServer may be embedded to opt out of forward compatibility for this service.
@@ -87,6 +175,86 @@ func _P2P_OnReceiveMessage_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _P2P_OnReceiveAbort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Abort)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2PServer).OnReceiveAbort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.P2P/OnReceiveAbort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2PServer).OnReceiveAbort(ctx, req.(*Abort))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _P2P_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(P2PServer).Stream(&p2PStreamServer{stream})
+}
+
+type P2P_StreamServer interface {
+	Send(*Ack) error
+	Recv() (*Frame, error)
+	grpc.ServerStream
+}
+
+type p2PStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *p2PStreamServer) Send(m *Ack) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *p2PStreamServer) Recv() (*Frame, error) {
+	m := new(Frame)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _P2P_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Hello)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2PServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.P2P/Handshake",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2PServer).Handshake(ctx, req.(*Hello))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _P2P_SignRoster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Roster)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2PServer).SignRoster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.P2P/SignRoster",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2PServer).SignRoster(ctx, req.(*Roster))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // P2P_ServiceDesc is the grpc.ServiceDesc for P2P service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -98,7 +266,187 @@ var P2P_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "OnReceiveMessage",
 			Handler:    _P2P_OnReceiveMessage_Handler,
 		},
+		{
+			MethodName: "OnReceiveAbort",
+			Handler:    _P2P_OnReceiveAbort_Handler,
+		},
+		{
+			MethodName: "Handshake",
+			Handler:    _P2P_Handshake_Handler,
+		},
+		{
+			MethodName: "SignRoster",
+			Handler:    _P2P_SignRoster_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _P2P_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "p2p.proto",
+}
+
+// RelayClient is the client API for Relay service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RelayClient interface {
+	// store one frame for the party it goes to, see `to_pid` of its body. The ack only tells the frame is stored.
+	Send(ctx context.Context, in *Frame, opts ...grpc.CallOption) (*Ack, error)
+	// long-lived stream delivering the frames stored for the calling party, in order. The party acks each frame with
+	// the same seq, and the relay drops it then.
+	Receive(ctx context.Context, opts ...grpc.CallOption) (Relay_ReceiveClient, error)
+}
+
+type relayClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRelayClient(cc grpc.ClientConnInterface) RelayClient {
+	return &relayClient{cc}
+}
+
+func (c *relayClient) Send(ctx context.Context, in *Frame, opts ...grpc.CallOption) (*Ack, error) {
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, "/proto.Relay/Send", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relayClient) Receive(ctx context.Context, opts ...grpc.CallOption) (Relay_ReceiveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Relay_ServiceDesc.Streams[0], "/proto.Relay/Receive", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &relayReceiveClient{stream}
+	return x, nil
+}
+
+type Relay_ReceiveClient interface {
+	Send(*Ack) error
+	Recv() (*Frame, error)
+	grpc.ClientStream
+}
+
+type relayReceiveClient struct {
+	grpc.ClientStream
+}
+
+func (x *relayReceiveClient) Send(m *Ack) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *relayReceiveClient) Recv() (*Frame, error) {
+	m := new(Frame)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RelayServer is the server API for Relay service.
+// All implementations must embed UnimplementedRelayServer
+// for forward compatibility
+type RelayServer interface {
+	// store one frame for the party it goes to, see `to_pid` of its body. The ack only tells the frame is stored.
+	Send(context.Context, *Frame) (*Ack, error)
+	// long-lived stream delivering the frames stored for the calling party, in order. The party acks each frame with
+	// the same seq, and the relay drops it then.
+	Receive(Relay_ReceiveServer) error
+	mustEmbedUnimplementedRelayServer()
+}
+
+// UnimplementedRelayServer must be embedded to have forward compatible implementations.
+type UnimplementedRelayServer struct {
+}
+
+func (UnimplementedRelayServer) Send(context.Context, *Frame) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Send not implemented")
+}
+func (UnimplementedRelayServer) Receive(Relay_ReceiveServer) error {
+	return status.Errorf(codes.Unimplemented, "method Receive not implemented")
+}
+func (UnimplementedRelayServer) mustEmbedUnimplementedRelayServer() {}
+
+// UnsafeRelayServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RelayServer will
+// result in compilation errors.
+type UnsafeRelayServer interface {
+	mustEmbedUnimplementedRelayServer()
+}
+
+func RegisterRelayServer(s grpc.ServiceRegistrar, srv RelayServer) {
+	s.RegisterService(&Relay_ServiceDesc, srv)
+}
+
+func _Relay_Send_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Frame)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelayServer).Send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Relay/Send",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelayServer).Send(ctx, req.(*Frame))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Relay_Receive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RelayServer).Receive(&relayReceiveServer{stream})
+}
+
+type Relay_ReceiveServer interface {
+	Send(*Frame) error
+	Recv() (*Ack, error)
+	grpc.ServerStream
+}
+
+type relayReceiveServer struct {
+	grpc.ServerStream
+}
+
+func (x *relayReceiveServer) Send(m *Frame) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *relayReceiveServer) Recv() (*Ack, error) {
+	m := new(Ack)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Relay_ServiceDesc is the grpc.ServiceDesc for Relay service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Relay_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.Relay",
+	HandlerType: (*RelayServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Send",
+			Handler:    _Relay_Send_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Receive",
+			Handler:       _Relay_Receive_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "p2p.proto",
 }
@@ -0,0 +1,190 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+)
+
+const (
+	// bounds of the frames stored for one party, so a peer can't make the relay store frames forever
+	maxMailboxSessions = 64
+	maxSessionFrames   = 1024
+	frameTTL           = 10 * time.Minute
+
+	// wait before sending the frame the party is too busy to take again
+	retryDelay = time.Second
+)
+
+var errReplaced = errors.New("replaced by another stream of the same party")
+
+type stored struct {
+	frame     *pb.Frame
+	sessionID string
+	received  time.Time
+
+	// not sent again before this time, once the party is too busy to take it
+	notBefore time.Time
+}
+
+// mailbox holds the frames for one party, in the order they are received, until the party acks them.
+type mailbox struct {
+	mu sync.Mutex
+
+	// last seq assigned to the stored frames
+	seq uint64
+
+	frames []*stored
+
+	// frames[:sent] have been sent to the current receiver, waiting for their acks
+	sent int
+
+	// current receiving stream, a newer stream takes over from the older one
+	receiver uint64
+
+	// closed and replaced once anything changes, to wake the receiver up
+	wake chan struct{}
+}
+
+func newMailbox() *mailbox {
+	return &mailbox{wake: make(chan struct{})}
+}
+
+func (mb *mailbox) notify() {
+	close(mb.wake)
+	mb.wake = make(chan struct{})
+}
+
+// put stores the frame for delivery.
+func (mb *mailbox) put(frame *pb.Frame) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	now := time.Now()
+	mb.expire(now)
+
+	sessionID := frame.SessionId()
+	sessions, frames := make(map[string]struct{}), 0
+	for _, s := range mb.frames {
+		sessions[s.sessionID] = struct{}{}
+		if s.sessionID == sessionID {
+			frames++
+		}
+	}
+	if _, ok := sessions[sessionID]; !ok && len(sessions) >= maxMailboxSessions {
+		return fmt.Errorf("too many sessions waiting for party %s", frame.ToPid())
+	}
+	if frames >= maxSessionFrames {
+		return fmt.Errorf("too many frames of session %s waiting for party %s", sessionID, frame.ToPid())
+	}
+
+	mb.seq++
+	frame.Seq = mb.seq
+	mb.frames = append(mb.frames, &stored{frame: frame, sessionID: sessionID, received: now})
+	mb.notify()
+	return nil
+}
+
+// expire drops the frames nobody receives in time, i.e, the party is gone.
+func (mb *mailbox) expire(now time.Time) {
+	frames, sent := mb.frames[:0], mb.sent
+	for i, s := range mb.frames {
+		if now.Sub(s.received) < frameTTL {
+			frames = append(frames, s)
+		} else if i < mb.sent {
+			sent--
+		}
+	}
+	clear(mb.frames[len(frames):])
+	mb.frames, mb.sent = frames, sent
+}
+
+// attach makes a new stream the receiver, all frames not acked yet are sent to it again.
+func (mb *mailbox) attach() uint64 {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	mb.receiver++
+	mb.sent = 0
+	mb.notify()
+	return mb.receiver
+}
+
+// next returns the next frame to send to the receiver, waiting until there is one.
+func (mb *mailbox) next(ctx context.Context, receiver uint64) (*pb.Frame, error) {
+	for {
+		mb.mu.Lock()
+		if mb.receiver != receiver {
+			mb.mu.Unlock()
+			return nil, errReplaced
+		}
+		// the first frame not sent yet, skipping the frames waiting to be retried
+		var delay <-chan time.Time
+		now := time.Now()
+		for i := mb.sent; i < len(mb.frames); i++ {
+			s := mb.frames[i]
+			if wait := s.notBefore.Sub(now); wait > 0 {
+				if delay == nil {
+					delay = time.After(wait)
+				}
+				continue
+			}
+			// move it right after the sent frames, so frames[:sent] are still the sent ones
+			copy(mb.frames[mb.sent+1:i+1], mb.frames[mb.sent:i])
+			mb.frames[mb.sent] = s
+			mb.sent++
+			mb.mu.Unlock()
+			return s.frame, nil
+		}
+		wake := mb.wake
+		mb.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wake:
+		case <-delay:
+		}
+	}
+}
+
+// retry sends the frame again after `retryDelay`, the party is too busy to take it now. It goes after all other
+// frames, so the frames the party can take now are not held up.
+func (mb *mailbox) retry(seq uint64) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	for i, s := range mb.frames {
+		if s.frame.GetSeq() != seq {
+			continue
+		}
+		mb.frames = append(append(mb.frames[:i], mb.frames[i+1:]...), s)
+		if i < mb.sent {
+			mb.sent--
+		}
+		s.notBefore = time.Now().Add(retryDelay)
+		mb.notify()
+		return
+	}
+}
+
+// ack drops the delivered frame.
+func (mb *mailbox) ack(seq uint64) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	for i, s := range mb.frames {
+		if s.frame.GetSeq() != seq {
+			continue
+		}
+		mb.frames = append(mb.frames[:i], mb.frames[i+1:]...)
+		if i < mb.sent {
+			mb.sent--
+		}
+		return
+	}
+}
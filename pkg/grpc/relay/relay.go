@@ -0,0 +1,173 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/creds"
+)
+
+// ServerName is the name of the relay certificate
+const ServerName = "relay"
+
+// Serve runs the relay at the given local port, for the parties of the roster. Mutual tls is required, the client
+// certificate tells which party is calling.
+func Serve(port string, creds credentials.TransportCredentials, parties []string) error {
+	log.Printf("relay listens at local port: %v", port)
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		return err
+	}
+	var keep = keepalive.EnforcementPolicy{
+		MinTime:             5 * time.Second, // If a client pings more than once every 5 seconds, terminate the connection
+		PermitWithoutStream: true,            // Allow pings even when there are no active streams
+	}
+
+	var kasp = keepalive.ServerParameters{
+		Time:    5 * time.Second, // Ping the client if it is idle for 5 seconds to ensure the connection is still active
+		Timeout: 1 * time.Second, // Wait 1 second for the ping ack before assuming the connection is dead
+	}
+	s := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.KeepaliveEnforcementPolicy(keep),
+		grpc.KeepaliveParams(kasp),
+	)
+	pb.RegisterRelayServer(s, &server{parties: parties, mailboxes: make(map[string]*mailbox)})
+	return s.Serve(lis)
+}
+
+// Dial connects to the relay at the given address. The connection is made lazily and kept up by grpc, so a party
+// may start before the relay. With tls, the relay certificate must be issued for `ServerName`.
+func Dial(addr string, creds credentials.TransportCredentials) (pb.RelayClient, error) {
+	var kacp = keepalive.ClientParameters{
+		Time:                10 * time.Second, // send pings every 10 seconds if there is no activity
+		Timeout:             time.Second,      // wait 1 second for ping ack before considering the connection dead
+		PermitWithoutStream: true,             // send pings even without active streams
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithAuthority(ServerName),
+		grpc.WithKeepaliveParams(kacp),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewRelayClient(conn), nil
+}
+
+// server stores and forwards the frames between parties. It only reads the envelopes to route them, the content of
+// point to point messages is encrypted to their receivers.
+type server struct {
+	pb.UnimplementedRelayServer
+
+	// party unique ids allowed to use this relay
+	parties []string
+
+	// stored frames, key is the receiver party unique id
+	mu        sync.Mutex
+	mailboxes map[string]*mailbox
+}
+
+func (s *server) mailbox(pid string) *mailbox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mb, ok := s.mailboxes[pid]
+	if !ok {
+		mb = newMailbox()
+		s.mailboxes[pid] = mb
+	}
+	return mb
+}
+
+func (s *server) Send(ctx context.Context, frame *pb.Frame) (*pb.Ack, error) {
+	// parties may only send their own frames. The receivers verify the envelope signatures anyway.
+	id, err := s.partyID(ctx)
+	if err != nil {
+		return &pb.Ack{Error: err.Error()}, nil
+	}
+	if id != frame.FromPid() {
+		return &pb.Ack{Error: fmt.Sprintf("party %s is not allowed to send frame from party %s", id, frame.FromPid())}, nil
+	}
+	to := frame.ToPid()
+	if !slices.Contains(s.parties, to) {
+		return &pb.Ack{Error: fmt.Sprintf("unexpected receiver party: %s", to)}, nil
+	}
+
+	// a full mailbox might drain once the receiver comes back, so the sender retries
+	if err := s.mailbox(to).put(frame); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return &pb.Ack{}, nil
+}
+
+// Receive delivers the stored frames to the calling party, until the stream breaks or another stream of the same
+// party takes over. Frames not acked yet are delivered again over the next stream.
+func (s *server) Receive(stream pb.Relay_ReceiveServer) error {
+	pid, err := s.partyID(stream.Context())
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	log.Printf("party %s connected", pid)
+	defer log.Printf("party %s disconnected", pid)
+
+	mb := s.mailbox(pid)
+	receiver := mb.attach()
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			ack, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			if ack.GetRetryable() {
+				mb.retry(ack.GetSeq())
+				continue
+			}
+			if ack.GetError() != "" {
+				log.Printf("party %s fails processing frame %d: %s", pid, ack.GetSeq(), ack.GetError())
+			}
+			mb.ack(ack.GetSeq())
+		}
+	}()
+
+	for {
+		frame, err := mb.next(ctx, receiver)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(frame); err != nil {
+			return err
+		}
+	}
+}
+
+// partyID returns which party is calling, the party of the client certificate. Any caller could claim any party
+// otherwise, and drain its frames.
+func (s *server) partyID(ctx context.Context) (string, error) {
+	id, ok := creds.PartyID(ctx)
+	if !ok {
+		return "", errors.New("client certificate is required")
+	}
+	if !slices.Contains(s.parties, id) {
+		return "", fmt.Errorf("unexpected party: %q", id)
+	}
+	return id, nil
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// keep the delivered message ids long enough to cover all retries of one message
+	deliveredTTL = 10 * time.Minute
+
+	// bound of the kept message ids, so a peer can't make this node keep ids forever
+	maxDelivered = 100000
+)
+
+// delivered holds the ids of the messages processed by the local party, so a message retried by its sender, i.e,
+// after its ack is lost, is only processed once.
+type delivered struct {
+	mu sync.Mutex
+
+	// key is sender party unique id + message id
+	m map[string]time.Time
+}
+
+func newDelivered() *delivered {
+	return &delivered{m: make(map[string]time.Time)}
+}
+
+// mark marks the message as delivered, and returns false if it's delivered already. Messages without id are always
+// delivered.
+func (d *delivered) mark(fromPID, messageID string) bool {
+	if messageID == "" {
+		return true
+	}
+	key := fromPID + "/" + messageID
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if at, ok := d.m[key]; ok && now.Sub(at) < deliveredTTL {
+		return false
+	}
+	if len(d.m) >= maxDelivered {
+		d.expire(now)
+	}
+	d.m[key] = now
+	return true
+}
+
+// unmark forgets the message failing to process, so its retry is processed again.
+func (d *delivered) unmark(fromPID, messageID string) {
+	if messageID == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.m, fromPID+"/"+messageID)
+}
+
+// expire drops the expired ids, and then arbitrary ones if there is still no room.
+func (d *delivered) expire(now time.Time) {
+	for key, at := range d.m {
+		if now.Sub(at) >= deliveredTTL {
+			delete(d.m, key)
+		}
+	}
+	for key := range d.m {
+		if len(d.m) < maxDelivered {
+			return
+		}
+		delete(d.m, key)
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/discovery"
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/creds"
+)
+
+// Handshake records the announcement of the calling party, and returns the local one.
+func (s *server) Handshake(ctx context.Context, hello *pb.Hello) (*pb.Hello, error) {
+	if s.discovery == nil {
+		return nil, status.Error(codes.Unimplemented, "handshake is disabled on this party")
+	}
+	// the announcement is checked against the party of the client certificate, if tls is enabled
+	peer, _ := creds.PartyID(ctx)
+	resp, err := s.discovery.OnHandshake(ctx, peer, hello)
+	if err != nil {
+		log.Printf("error processing party on handshake: %v", err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return resp, nil
+}
+
+// SignRoster signs the roster, once the local party has the announcements of all parties.
+func (s *server) SignRoster(ctx context.Context, roster *pb.Roster) (*pb.RosterSignature, error) {
+	if s.discovery == nil {
+		return nil, status.Error(codes.Unimplemented, "handshake is disabled on this party")
+	}
+	sig, err := s.discovery.OnSignRoster(ctx, roster)
+	if errors.Is(err, discovery.ErrNotReady) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		log.Printf("error processing party on sign roster: %v", err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return sig, nil
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+// Inbox reads the frames for the local party from files within one dir, instead of listening for other parties.
+// It's for air-gapped parties, the files written by `client.NewFileClient` of other parties are carried over by hand,
+// i.e, by USB. Processed files are moved into the `processed` sub dir, and files failing to process into `failed`.
+type Inbox struct {
+	s   *server
+	dir string
+}
+
+func NewInbox(dir string, id string, party party.Party, identity *identity.Identity, registry identity.Registry) (*Inbox, error) {
+	for _, sub := range []string{"processed", "failed"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, fmt.Errorf("error creating inbox dir: %w", err)
+		}
+	}
+	return &Inbox{
+		s:   &server{id: id, party: party, identity: identity, registry: registry, delivered: newDelivered()},
+		dir: dir,
+	}, nil
+}
+
+// errRetryable is returned when the frame can't be processed for now, but may be processed later.
+var errRetryable = errors.New("frame is not processed for now")
+
+// Ingest processes all frame files within the inbox dir in the order they are written, and returns how many of them
+// are processed.
+func (in *Inbox) Ingest(ctx context.Context) (int, error) {
+	entries, err := os.ReadDir(in.dir)
+	if err != nil {
+		return 0, fmt.Errorf("error reading inbox dir: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), client.FrameFileExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		sub := "processed"
+		err := in.ingest(ctx, filepath.Join(in.dir, name))
+		if errors.Is(err, errRetryable) {
+			// the file stays within the inbox, and is ingested again next time
+			log.Printf("frame file %s is left for the next ingest: %v", name, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("error ingesting frame file %s: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			sub = "failed"
+		}
+		if err := os.Rename(filepath.Join(in.dir, name), filepath.Join(in.dir, sub, name)); err != nil {
+			return len(names), fmt.Errorf("error moving frame file: %w", err)
+		}
+	}
+	return len(names), errors.Join(errs...)
+}
+
+func (in *Inbox) ingest(ctx context.Context, path string) error {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	frame := &pb.Frame{}
+	if err := proto.Unmarshal(bz, frame); err != nil {
+		return fmt.Errorf("error unmarshalling frame: %w", err)
+	}
+	ack := in.s.receiveFrame(ctx, frame)
+	if ack.GetRetryable() {
+		return fmt.Errorf("%w: %s", errRetryable, ack.GetError())
+	}
+	if ack.GetError() != "" {
+		return errors.New(ack.GetError())
+	}
+	return nil
+}
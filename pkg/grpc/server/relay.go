@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+// wait before reconnecting to the relay
+const relayReconnectInterval = 2 * time.Second
+
+// ServeRelay receives the frames for the local party from the relay, instead of listening for other parties. It
+// reconnects whenever the stream breaks, and only returns once ctx is done.
+// The relay may be anyone, so identities are required to verify the frames it delivers.
+func ServeRelay(ctx context.Context, id string, party party.Party, relay pb.RelayClient, identity *identity.Identity, registry identity.Registry) error {
+	if identity == nil || registry == nil {
+		return errors.New("identity and registry are required with relay")
+	}
+	s := &server{id: id, party: party, identity: identity, registry: registry, delivered: newDelivered()}
+	for {
+		err := s.receiveRelay(ctx, relay)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("error receiving frames from relay, reconnect in %v: %v", relayReconnectInterval, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(relayReconnectInterval):
+		}
+	}
+}
+
+// receiveRelay processes the frames delivered by the relay in order, and acks each of them, until the stream breaks.
+func (s *server) receiveRelay(ctx context.Context, r pb.RelayClient) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := r.Receive(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("connected to relay")
+	for {
+		frame, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(s.receiveFrame(ctx, frame)); err != nil {
+			return err
+		}
+	}
+}
@@ -2,25 +2,38 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/smiletrl/tss-lib-starter/pkg/config"
 	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/discovery"
 	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/creds"
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
 	"github.com/smiletrl/tss-lib-starter/pkg/party"
 	"google.golang.org/grpc"
 )
 
-// Register the rpc server for p2p service.
-func RegisterServer(id string, party party.Party) error {
-	port := fmt.Sprintf(":%s", constants.TestGrpcHost[id][1])
+// Register the rpc server for p2p service. With discovery set, it answers the handshakes of the other parties.
+func RegisterServer(cfg *config.Config, id string, party party.Party, creds credentials.TransportCredentials, identity *identity.Identity, registry identity.Registry, discovery *discovery.Discovery) error {
+	port, err := cfg.Port(id)
+	if err != nil {
+		return err
+	}
+	port = fmt.Sprintf(":%s", port)
 
 	log.Printf("grpc server listens at local port: %v", port)
 
@@ -34,13 +47,13 @@ func RegisterServer(id string, party party.Party) error {
 	}
 
 	var kasp = keepalive.ServerParameters{
-		MaxConnectionIdle:     15 * time.Second, // If a client is idle for 15 seconds, send a GOAWAY
-		MaxConnectionAge:      30 * time.Second, // If any connection is alive for more than 30 seconds, send a GOAWAY
-		MaxConnectionAgeGrace: 5 * time.Second,  // Allow 5 seconds for pending RPCs to complete before forcibly closing connections
-		Time:                  5 * time.Second,  // Ping the client if it is idle for 5 seconds to ensure the connection is still active
-		Timeout:               1 * time.Second,  // Wait 1 second for the ping ack before assuming the connection is dead
+		MaxConnectionIdle: 15 * time.Second, // If a client is idle for 15 seconds, send a GOAWAY
+		// No MaxConnectionAge, so the long-lived streams between parties are not closed in the middle of a ceremony
+		Time:    5 * time.Second, // Ping the client if it is idle for 5 seconds to ensure the connection is still active
+		Timeout: 1 * time.Second, // Wait 1 second for the ping ack before assuming the connection is dead
 	}
 	s := grpc.NewServer(
+		grpc.Creds(creds),
 		grpc.KeepaliveEnforcementPolicy(keep),
 		grpc.KeepaliveParams(kasp),
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
@@ -51,7 +64,7 @@ func RegisterServer(id string, party party.Party) error {
 			grpc_opentracing.UnaryServerInterceptor(),
 		)),
 	)
-	pb.RegisterP2PServer(s, &server{party: party})
+	pb.RegisterP2PServer(s, &server{id: id, party: party, identity: identity, registry: registry, discovery: discovery, delivered: newDelivered()})
 	if err := s.Serve(lis); err != nil {
 		return err
 	}
@@ -62,13 +75,183 @@ func RegisterServer(id string, party party.Party) error {
 type server struct {
 	pb.UnimplementedP2PServer
 	party party.Party
+
+	// local party unique id
+	id string
+
+	// local identity key decrypting point to point messages
+	identity *identity.Identity
+
+	// identity keys of all parties verifying the envelopes, no verification if nil
+	registry identity.Registry
+
+	// agrees on the roster with the other parties, no handshake if nil
+	discovery *discovery.Discovery
+
+	// ids of the processed messages, to drop retried duplicates
+	delivered *delivered
+}
+
+// checkSender makes sure the message comes from the party of the client certificate, if tls is enabled.
+func checkSender(ctx context.Context, fromPID string) error {
+	id, ok := creds.PartyID(ctx)
+	if !ok {
+		return nil
+	}
+	if id != fromPID {
+		return fmt.Errorf("party %s is not allowed to send message from party %s", id, fromPID)
+	}
+	return nil
+}
+
+// verify makes sure the envelope goes to this node, and is signed by the identity key of its sender.
+func (s *server) verify(fromPID, toPID string, signingBytes, signature []byte) error {
+	if s.registry == nil {
+		return nil
+	}
+	if toPID != s.id {
+		return fmt.Errorf("unexpected envelope to party %s, local party is %s", toPID, s.id)
+	}
+	if err := s.registry.Verify(fromPID, signingBytes, signature); err != nil {
+		return fmt.Errorf("error verifying envelope: %w", err)
+	}
+	return nil
+}
+
+// open decrypts the content of point to point messages. With identities set, point to point messages must be
+// encrypted.
+func (s *server) open(msg *pb.Message) ([]byte, error) {
+	if !msg.GetEncrypted() {
+		if s.identity != nil && !msg.GetIsBroadcast() {
+			return nil, fmt.Errorf("unexpected unencrypted point to point message from party %s", msg.GetFromPid())
+		}
+		return msg.GetContent(), nil
+	}
+	if s.identity == nil {
+		return nil, fmt.Errorf("no identity to decrypt message from party %s", msg.GetFromPid())
+	}
+	return s.identity.Open(s.registry, msg.GetFromPid(), msg.GetContent(), msg.AdditionalData())
 }
 
 func (s *server) OnReceiveMessage(ctx context.Context, msg *pb.Message) (*emptypb.Empty, error) {
+	if err := s.receiveMessage(ctx, msg); err != nil {
+		return nil, toStatus(err)
+	}
+	return nil, nil
+}
+
+func (s *server) OnReceiveAbort(ctx context.Context, abort *pb.Abort) (*emptypb.Empty, error) {
+	if err := s.receiveAbort(ctx, abort); err != nil {
+		return nil, toStatus(err)
+	}
+	return nil, nil
+}
+
+// toStatus tells the caller to retry later if the local party is too busy to take the message now.
+func toStatus(err error) error {
+	if errors.Is(err, party.ErrQueueFull) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
+// Stream processes the frames from one party in order, and acks each of them.
+func (s *server) Stream(stream pb.P2P_StreamServer) error {
+	ctx := stream.Context()
+	for {
+		frame, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(s.receiveFrame(ctx, frame)); err != nil {
+			return err
+		}
+	}
+}
+
+// receiveFrame processes one frame, and returns its ack.
+func (s *server) receiveFrame(ctx context.Context, frame *pb.Frame) *pb.Ack {
+	var err error
+	switch body := frame.GetBody().(type) {
+	case *pb.Frame_Message:
+		err = s.receiveMessage(ctx, body.Message)
+	case *pb.Frame_Abort:
+		err = s.receiveAbort(ctx, body.Abort)
+	default:
+		err = fmt.Errorf("unexpected frame body: %T", body)
+	}
+	ack := &pb.Ack{Seq: frame.GetSeq()}
+	if err != nil {
+		ack.Error = err.Error()
+		ack.Retryable = errors.Is(err, party.ErrQueueFull)
+	}
+	return ack
+}
+
+func (s *server) receiveMessage(ctx context.Context, msg *pb.Message) error {
+	if err := checkSender(ctx, msg.GetFromPid()); err != nil {
+		log.Printf("error processing party on receive message: %v", err)
+		return err
+	}
+	if err := s.verify(msg.GetFromPid(), msg.GetToPid(), msg.SigningBytes(), msg.GetSignature()); err != nil {
+		log.Printf("error processing party on receive message: %v", err)
+		return err
+	}
+	content, err := s.open(msg)
+	if err != nil {
+		log.Printf("error processing party on receive message: %v", err)
+		return err
+	}
+
+	// the sender retries when the ack is lost, the retry is acked again without processing it twice
+	if !s.delivered.mark(msg.GetFromPid(), msg.GetMessageId()) {
+		log.Printf("duplicate message %s from party %s dropped", msg.GetMessageId(), msg.GetFromPid())
+		return nil
+	}
+	if err := s.process(ctx, msg, content); err != nil {
+		s.delivered.unmark(msg.GetFromPid(), msg.GetMessageId())
+		return err
+	}
+	return nil
+}
+
+// process hands the message over to the local party.
+func (s *server) process(ctx context.Context, msg *pb.Message, content []byte) error {
+
+	// resharing messages are routed to the local party within the old or the new committee
+	if constants.MessageType(msg.GetType()) == constants.MessageTypeResharing {
+		if err := s.party.OnReceiveReshareMessage(ctx, msg.GetSessionId(), msg.GetFromKey(), msg.GetToOldCommittee(), msg.GetToOldAndNewCommittees(), msg.GetIsBroadcast(), content); err != nil {
+			log.Printf("error processing party on receive reshare message: %v", err)
+			return fmt.Errorf("error processing party on receive reshare message: %w", err)
+		}
+		return nil
+	}
+
 	// update local party data
-	if err := s.party.OnReceiveMessage(ctx, constants.MessageType(msg.GetType()), msg.GetFromPid(), msg.GetIsBroadcast(), msg.GetContent()); err != nil {
+	if err := s.party.OnReceiveMessage(ctx, msg.GetSessionId(), constants.MessageType(msg.GetType()), msg.GetFromPid(), msg.GetParties(), msg.GetIsBroadcast(), content); err != nil {
 		log.Printf("error processing party on receive message: %v", err)
-		return nil, fmt.Errorf("error processing party on receive message: %w", err)
+		return fmt.Errorf("error processing party on receive message: %w", err)
 	}
-	return nil, nil
+	return nil
+}
+
+func (s *server) receiveAbort(ctx context.Context, abort *pb.Abort) error {
+	if err := checkSender(ctx, abort.GetFromPid()); err != nil {
+		log.Printf("error processing party on receive abort: %v", err)
+		return err
+	}
+	if err := s.verify(abort.GetFromPid(), abort.GetToPid(), abort.SigningBytes(), abort.GetSignature()); err != nil {
+		log.Printf("error processing party on receive abort: %v", err)
+		return err
+	}
+
+	if err := s.party.OnReceiveAbort(ctx, abort.GetSessionId(), constants.MessageType(abort.GetType()), abort.GetFromPid(), abort.GetCulprits(), abort.GetReason()); err != nil {
+		log.Printf("error processing party on receive abort: %v", err)
+		return fmt.Errorf("error processing party on receive abort: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,93 @@
+package identity
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// domain separation of the keys derived from the identity seed
+const (
+	boxKeyDomain    = "tss-lib-starter/box-key/v1"
+	sharedKeyDomain = "tss-lib-starter/shared-key/v1"
+)
+
+// boxKey derives the x25519 key from the identity seed, so one seed backs up both keys.
+func boxKey(seed []byte) (*ecdh.PrivateKey, error) {
+	h := sha256.New()
+	h.Write([]byte(boxKeyDomain))
+	h.Write(seed)
+	key, err := ecdh.X25519().NewPrivateKey(h.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("error deriving box key: %w", err)
+	}
+	return key, nil
+}
+
+// sharedKey derives the symmetric key of the messages from one party to another. Each direction has its own key.
+func (i *Identity) sharedKey(r Registry, peer string, from, to string) ([]byte, error) {
+	e, ok := r[peer]
+	if !ok {
+		return nil, fmt.Errorf("unregistered party: %s", peer)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(e.BoxKey)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected box key of party %s: %w", peer, err)
+	}
+	secret, err := i.box.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving shared secret with party %s: %w", peer, err)
+	}
+
+	info := []byte(sharedKeyDomain + "|" + from + "|" + to)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, info), key); err != nil {
+		return nil, fmt.Errorf("error deriving shared key: %w", err)
+	}
+	return key, nil
+}
+
+// Seal encrypts the plaintext to the party, so only this party can decrypt it. The additional data is authenticated
+// but not encrypted. The random nonce is prepended to the ciphertext.
+func (i *Identity) Seal(r Registry, to string, plaintext, additionalData []byte) ([]byte, error) {
+	key, err := i.sharedKey(r, to, i.ID, to)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts the ciphertext from the party, sealed by `Seal` with the same additional data.
+func (i *Identity) Open(r Registry, from string, ciphertext, additionalData []byte) ([]byte, error) {
+	key, err := i.sharedKey(r, from, from, i.ID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting message from party %s: %w", from, err)
+	}
+	return plaintext, nil
+}
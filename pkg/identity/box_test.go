@@ -0,0 +1,73 @@
+package identity
+
+import (
+	"bytes"
+	"testing"
+)
+
+// newIdentities generates the identities of the given parties, and the registry of all of them.
+func newIdentities(t *testing.T, ids ...string) (map[string]*Identity, Registry) {
+	t.Helper()
+
+	identities := make(map[string]*Identity, len(ids))
+	r := make(Registry, len(ids))
+	for _, id := range ids {
+		i, err := GenerateIdentity(id)
+		if err != nil {
+			t.Fatalf("error generating identity: %v", err)
+		}
+		identities[id] = i
+		r[id] = i.Entry()
+	}
+	return identities, r
+}
+
+func TestSealOpen(t *testing.T) {
+	identities, r := newIdentities(t, "p1", "p2", "p3")
+	plaintext, additionalData := []byte("round 1 share"), []byte("session")
+	ciphertext, err := identities["p1"].Seal(r, "p2", plaintext, additionalData)
+	if err != nil {
+		t.Fatalf("error sealing: %v", err)
+	}
+
+	got, err := identities["p2"].Open(r, "p1", ciphertext, additionalData)
+	if err != nil {
+		t.Fatalf("error opening: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("plaintext %q, want %q", got, plaintext)
+	}
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+
+	// p2 with another identity key, i.e, a new device without the registered key
+	other, err := GenerateIdentity("p2")
+	if err != nil {
+		t.Fatalf("error generating identity: %v", err)
+	}
+	identities["other p2"] = other
+	tests := []struct {
+		name           string
+		receiver       string
+		from           string
+		registry       Registry
+		ciphertext     []byte
+		additionalData []byte
+	}{
+		{name: "tampered ciphertext", receiver: "p2", from: "p1", registry: r, ciphertext: tampered, additionalData: additionalData},
+		{name: "truncated ciphertext", receiver: "p2", from: "p1", registry: r, ciphertext: ciphertext[:10], additionalData: additionalData},
+		{name: "other additional data", receiver: "p2", from: "p1", registry: r, ciphertext: ciphertext, additionalData: []byte("other session")},
+		{name: "wrong recipient", receiver: "p3", from: "p1", registry: r, ciphertext: ciphertext, additionalData: additionalData},
+		{name: "wrong recipient key", receiver: "other p2", from: "p1", registry: r, ciphertext: ciphertext, additionalData: additionalData},
+		{name: "wrong sender", receiver: "p2", from: "p3", registry: r, ciphertext: ciphertext, additionalData: additionalData},
+		{name: "unregistered sender", receiver: "p2", from: "p4", registry: r, ciphertext: ciphertext, additionalData: additionalData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := identities[tt.receiver].Open(tt.registry, tt.from, tt.ciphertext, tt.additionalData); err == nil {
+				t.Fatal("opened, want error")
+			}
+		})
+	}
+}
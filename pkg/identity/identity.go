@@ -0,0 +1,139 @@
+package identity
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Identity is the long-term identity key of one party, signing all envelopes this party sends.
+// !Important the seed is saved in plain text here. In real prod env, it should be encrypted, or kept within a
+// secure enclave/KMS.
+type Identity struct {
+	ID   string `json:"id"`
+	Seed []byte `json:"seed"`
+
+	key ed25519.PrivateKey
+
+	// x25519 key derived from the seed, encrypting the point to point messages between parties
+	box *ecdh.PrivateKey
+}
+
+func NewIdentity(id string, seed []byte) (*Identity, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("unexpected identity seed size: %d", len(seed))
+	}
+	box, err := boxKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		ID:   id,
+		Seed: seed,
+		key:  ed25519.NewKeyFromSeed(seed),
+		box:  box,
+	}, nil
+}
+
+// GenerateIdentity generates a new random identity for the party.
+func GenerateIdentity(id string) (*Identity, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("error generating identity seed: %w", err)
+	}
+	return NewIdentity(id, seed)
+}
+
+func LoadIdentity(file string) (*Identity, error) {
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading identity: %w", err)
+	}
+	i := &Identity{}
+	if err := json.Unmarshal(bz, i); err != nil {
+		return nil, fmt.Errorf("error unmarshaling identity: %w", err)
+	}
+	return NewIdentity(i.ID, i.Seed)
+}
+
+func (i *Identity) Save(file string) error {
+	bz, err := json.Marshal(i)
+	if err != nil {
+		return fmt.Errorf("error marshaling identity: %w", err)
+	}
+	if err := os.WriteFile(file, bz, 0600); err != nil {
+		return fmt.Errorf("error writing identity: %w", err)
+	}
+	return nil
+}
+
+// Entry returns the public identity of this party, to be registered with all other parties.
+func (i *Identity) Entry() Entry {
+	return Entry{
+		SignKey: i.key.Public().(ed25519.PublicKey),
+		BoxKey:  i.box.PublicKey().Bytes(),
+	}
+}
+
+func (i *Identity) Sign(msg []byte) []byte {
+	return ed25519.Sign(i.key, msg)
+}
+
+// Entry is the registered public identity of one party.
+type Entry struct {
+	// ed25519 public key verifying the envelopes signed by this party
+	SignKey []byte `json:"sign_key"`
+
+	// x25519 public key encrypting the messages to this party
+	BoxKey []byte `json:"box_key"`
+}
+
+// Registry holds the public identities of all parties, key is party unique id.
+type Registry map[string]Entry
+
+func LoadRegistry(file string) (Registry, error) {
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading identity registry: %w", err)
+	}
+	r := Registry{}
+	if err := json.Unmarshal(bz, &r); err != nil {
+		return nil, fmt.Errorf("error unmarshaling identity registry: %w", err)
+	}
+	for id, e := range r {
+		if len(e.SignKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unexpected sign key size of party %s: %d", id, len(e.SignKey))
+		}
+		if _, err := ecdh.X25519().NewPublicKey(e.BoxKey); err != nil {
+			return nil, fmt.Errorf("unexpected box key of party %s: %w", id, err)
+		}
+	}
+	return r, nil
+}
+
+func (r Registry) Save(file string) error {
+	bz, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling identity registry: %w", err)
+	}
+	if err := os.WriteFile(file, bz, 0644); err != nil {
+		return fmt.Errorf("error writing identity registry: %w", err)
+	}
+	return nil
+}
+
+// Verify verifies the signature of the party over the message, against its registered sign key.
+func (r Registry) Verify(id string, msg, sig []byte) error {
+	e, ok := r[id]
+	if !ok {
+		return fmt.Errorf("unregistered party: %s", id)
+	}
+	if !ed25519.Verify(e.SignKey, msg, sig) {
+		return errors.New("invalid signature of party " + id)
+	}
+	return nil
+}
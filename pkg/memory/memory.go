@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/bnb-chain/tss-lib/v2/tss"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+// Network dispatches messages between parties within the same process, i.e, for tests or single binary deployments.
+// Messages are handed over to the receiving party directly, without grpc.
+type Network struct {
+	// registered parties, key is party unique id
+	mu      sync.RWMutex
+	parties map[string]party.Party
+}
+
+func NewNetwork() *Network {
+	return &Network{
+		parties: make(map[string]party.Party),
+	}
+}
+
+// Register adds the party with the given unique id, so it receives the messages to it.
+func (n *Network) Register(id string, p party.Party) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.parties[id] = p
+}
+
+// NewClient returns the client sending messages to the parties of this network. It's passed to `party.NewParty`, and
+// the party itself is registered once it's created.
+func (n *Network) NewClient() client.Client {
+	return &memoryClient{network: n}
+}
+
+func (n *Network) party(id string) (party.Party, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	p, ok := n.parties[id]
+	if !ok {
+		return nil, fmt.Errorf("unexpected party unique id: %s", id)
+	}
+	return p, nil
+}
+
+// peers returns the unique ids of all registered parties except the given one.
+func (n *Network) peers(self string) []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	ids := make([]string, 0, len(n.parties))
+	for id := range n.parties {
+		if id != self {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// memoryClient is the client of one party within the network.
+type memoryClient struct {
+	network *Network
+
+	// party unique id
+	pid *tss.PartyID
+}
+
+func (c *memoryClient) WithPartyID(pid *tss.PartyID) {
+	c.pid = pid
+}
+
+func (c *memoryClient) BroadcastNodes(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, msg tss.Message) error {
+	bz, _, err := msg.WireBytes()
+	if err != nil {
+		return fmt.Errorf("error getting wire bytes: %w", err)
+	}
+
+	// resharing messages go to their own destinations, within the old or the new committee
+	if msgType == constants.MessageTypeResharing {
+		return c.toCommittee(ctx, sessionID, msg, bz)
+	}
+
+	var failures []client.DeliveryFailure
+	for _, id := range parties {
+		// should not send to itself
+		if id == msg.GetFrom().GetId() {
+			continue
+		}
+		failures = c.deliver(failures, id, func(p party.Party) error {
+			return p.OnReceiveMessage(ctx, sessionID, msgType, msg.GetFrom().GetId(), parties, true, bz)
+		})
+	}
+	return deliveryError(failures)
+}
+
+func (c *memoryClient) Announce(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, content []byte) error {
+	var failures []client.DeliveryFailure
+	for _, id := range parties {
+		// should not send to itself
+		if id == c.pid.GetId() {
+			continue
+		}
+		failures = c.deliver(failures, id, func(p party.Party) error {
+			return p.OnReceiveMessage(ctx, sessionID, msgType, c.pid.GetId(), parties, true, content)
+		})
+	}
+	return deliveryError(failures)
+}
+
+func (c *memoryClient) ToNode(ctx context.Context, sessionID string, parties []string, pid string, msgType constants.MessageType, msg tss.Message) error {
+	// the target node must join this session, i.e, be one of the signers
+	if !slices.Contains(parties, pid) {
+		return fmt.Errorf("unexpected to node request: %s", pid)
+	}
+
+	bz, _, err := msg.WireBytes()
+	if err != nil {
+		return fmt.Errorf("error getting wire bytes: %w", err)
+	}
+	var failures []client.DeliveryFailure
+	failures = c.deliver(failures, pid, func(p party.Party) error {
+		return p.OnReceiveMessage(ctx, sessionID, msgType, c.pid.GetId(), parties, false, bz)
+	})
+	return deliveryError(failures)
+}
+
+// toCommittee hands one resharing message over to all its destinations. One node may join both the old and the new
+// committee, so it only receives the message once, including when it's the node sending this message.
+func (c *memoryClient) toCommittee(ctx context.Context, sessionID string, msg tss.Message, bz []byte) error {
+	var failures []client.DeliveryFailure
+	sent := make(map[string]struct{}, len(msg.GetTo()))
+	for _, to := range msg.GetTo() {
+		pid := to.GetId()
+		if _, ok := sent[pid]; ok {
+			continue
+		}
+		sent[pid] = struct{}{}
+
+		failures = c.deliver(failures, pid, func(p party.Party) error {
+			return p.OnReceiveReshareMessage(ctx, sessionID, msg.GetFrom().GetKey(), msg.IsToOldCommittee(), msg.IsToOldAndNewCommittees(), msg.IsBroadcast(), bz)
+		})
+	}
+	return deliveryError(failures)
+}
+
+func (c *memoryClient) Abort(ctx context.Context, sessionID string, msgType constants.MessageType, culprits []string, reason string) error {
+	// nodes not joining this session just ignore it, so send to all nodes except itself
+	var failures []client.DeliveryFailure
+	for _, id := range c.network.peers(c.pid.GetId()) {
+		failures = c.deliver(failures, id, func(p party.Party) error {
+			return p.OnReceiveAbort(ctx, sessionID, msgType, c.pid.GetId(), culprits, reason)
+		})
+	}
+	return deliveryError(failures)
+}
+
+// deliver hands one message over to the party, and records the failure if it fails.
+func (c *memoryClient) deliver(failures []client.DeliveryFailure, pid string, receive func(p party.Party) error) []client.DeliveryFailure {
+	p, err := c.network.party(pid)
+	if err == nil {
+		err = receive(p)
+	}
+	if err != nil {
+		failures = append(failures, client.DeliveryFailure{PartyID: pid, Err: err})
+	}
+	return failures
+}
+
+// deliveryError returns the delivery error, or nil if all parties receive the message.
+func deliveryError(failures []client.DeliveryFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return &client.DeliveryError{Failures: failures}
+}
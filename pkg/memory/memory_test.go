@@ -0,0 +1,211 @@
+package memory_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/ceremony"
+	"github.com/smiletrl/tss-lib-starter/pkg/config"
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	"github.com/smiletrl/tss-lib-starter/pkg/memory"
+	"github.com/smiletrl/tss-lib-starter/pkg/party"
+)
+
+// node is one party within the test network, with its own key store.
+type node struct {
+	party    party.Party
+	keyStore party.KeyStore
+}
+
+// newNetwork builds all parties of the config over one in-memory network. Generating ecdsa pre-params takes minutes,
+// so each party reuses its own fixture pre-params within `testdata/pre-params`.
+func newNetwork(t *testing.T, cfg *config.Config) map[string]*node {
+	t.Helper()
+
+	network := memory.NewNetwork()
+	nodes := make(map[string]*node, len(cfg.Parties))
+	for _, pi := range cfg.Parties {
+		dir := t.TempDir()
+		bz, err := os.ReadFile(filepath.Join("testdata", "pre-params", pi.ID+".json"))
+		if err != nil {
+			t.Fatalf("error reading fixture pre-params: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, pi.ID+".json"), bz, 0600); err != nil {
+			t.Fatalf("error writing fixture pre-params: %v", err)
+		}
+		preParamsPool, err := party.NewPreParamsPool(dir, 0)
+		if err != nil {
+			t.Fatalf("error initializing pre-params pool: %v", err)
+		}
+		keyStore := party.NewMemoryKeyStore()
+		p := party.NewParty(cfg, network.NewClient(), keyStore, preParamsPool, 5*time.Minute)
+		if err := p.GatherSharedParties(cfg.Parties); err != nil {
+			t.Fatalf("error gathering shared parties: %v", err)
+		}
+		p.SetLocalID(pi.ID)
+		network.Register(pi.ID, p)
+		nodes[pi.ID] = &node{party: p, keyStore: keyStore}
+	}
+	return nodes
+}
+
+// run runs the ceremony on the given parties in parallel, and fails the test if any of them fails.
+func run(t *testing.T, ids []string, nodes map[string]*node, ceremony func(id string, n *node) error) {
+	t.Helper()
+
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ceremony(id, nodes[id])
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("party %s: %v", ids[i], err)
+		}
+	}
+}
+
+// keygen generates the key on all parties, and returns its public key once all of them hold a share of it.
+func keygen(t *testing.T, cfg *config.Config, nodes map[string]*node, keyID string, algorithm constants.Algorithm) []byte {
+	t.Helper()
+
+	sessionID := ceremony.KeygenSessionID(keyID)
+	run(t, cfg.PartyIDs(), nodes, func(id string, n *node) error {
+		return n.party.Keygen(context.Background(), sessionID, keyID, algorithm)
+	})
+
+	var publicKey []byte
+	for _, id := range cfg.PartyIDs() {
+		key, err := nodes[id].keyStore.Load(keyID)
+		if err != nil {
+			t.Fatalf("party %s: error loading key share: %v", id, err)
+		}
+		if publicKey == nil {
+			publicKey = key.PublicKey()
+		} else if !bytes.Equal(publicKey, key.PublicKey()) {
+			t.Fatalf("party %s: public key %x, want %x", id, key.PublicKey(), publicKey)
+		}
+	}
+	return publicKey
+}
+
+// sign signs the message by the signers, the others don't join. All signers must return the same signature, and it
+// must verify against the shared public key.
+func sign(t *testing.T, nodes map[string]*node, keyID string, signers []string, msgData []byte) {
+	t.Helper()
+
+	sessionID := ceremony.SigningSessionID(keyID, signers, msgData)
+	signatures := make(map[string][]byte, len(signers))
+	var mu sync.Mutex
+	run(t, signers, nodes, func(id string, n *node) error {
+		sig, err := n.party.Sign(context.Background(), sessionID, keyID, signers, msgData)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		signatures[id] = sig.GetSignature()
+		mu.Unlock()
+		return nil
+	})
+
+	key, err := nodes[signers[0]].keyStore.Load(keyID)
+	if err != nil {
+		t.Fatalf("party %s: error loading key share: %v", signers[0], err)
+	}
+	want := signatures[signers[0]]
+	if !key.VerifySignature(msgData, want) {
+		t.Fatalf("signature %x doesn't verify against public key %x", want, key.PublicKey())
+	}
+	for _, id := range signers {
+		if !bytes.Equal(signatures[id], want) {
+			t.Fatalf("party %s: signature %x, want %x", id, signatures[id], want)
+		}
+	}
+}
+
+func TestKeygenAndSign(t *testing.T) {
+	msg := []byte("hello tss")
+	hash := sha256.Sum256(msg)
+	tests := []struct {
+		algorithm constants.Algorithm
+		msgData   []byte
+	}{
+		// eddsa signs the message itself, and ecdsa signs its hash
+		{algorithm: constants.AlgorithmEdDSA, msgData: msg},
+		{algorithm: constants.AlgorithmECDSA, msgData: hash[:]},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			cfg := config.Default()
+			nodes := newNetwork(t, cfg)
+			keyID := "test-key"
+			keygen(t, cfg, nodes, keyID, tt.algorithm)
+
+			// any threshold+1 parties sign, the others don't join
+			sign(t, nodes, keyID, []string{"p1", "p3", "p4"}, tt.msgData)
+		})
+	}
+}
+
+func TestReshare(t *testing.T) {
+	cfg := config.Default()
+	nodes := newNetwork(t, cfg)
+	keyID := "test-key"
+	publicKey := keygen(t, cfg, nodes, keyID, constants.AlgorithmECDSA)
+
+	// p4 joins the new committee as a new device, without any share of the key
+	if err := nodes["p4"].keyStore.Delete(keyID); err != nil {
+		t.Fatalf("error deleting key share: %v", err)
+	}
+
+	reshare := func(oldIDs, newIDs []string, newThreshold int) {
+		t.Helper()
+
+		sessionID := ceremony.ResharingSessionID(keyID, oldIDs, newIDs, newThreshold)
+		ids := append(slices.Clone(oldIDs), slices.DeleteFunc(slices.Clone(newIDs), func(id string) bool {
+			return slices.Contains(oldIDs, id)
+		})...)
+		run(t, ids, nodes, func(id string, n *node) error {
+			return n.party.Reshare(context.Background(), sessionID, keyID, oldIDs, newIDs, newThreshold)
+		})
+	}
+	oldIDs, newIDs := []string{"p1", "p2", "p3"}, []string{"p2", "p3", "p4"}
+	reshare(oldIDs, newIDs, 1)
+
+	// p1 leaves the committee, its share is deleted
+	if _, err := nodes["p1"].keyStore.Load(keyID); !errors.Is(err, party.ErrKeyNotFound) {
+		t.Fatalf("party p1: error %v, want %v", err, party.ErrKeyNotFound)
+	}
+	// the new committee holds new shares of the same key
+	for _, id := range newIDs {
+		key, err := nodes[id].keyStore.Load(keyID)
+		if err != nil {
+			t.Fatalf("party %s: error loading key share: %v", id, err)
+		}
+		if !bytes.Equal(key.PublicKey(), publicKey) {
+			t.Fatalf("party %s: public key %x, want %x", id, key.PublicKey(), publicKey)
+		}
+		if key.Threshold != 1 {
+			t.Fatalf("party %s: threshold %d, want 1", id, key.Threshold)
+		}
+	}
+	hash := sha256.Sum256([]byte("hello tss"))
+	sign(t, nodes, keyID, []string{"p2", "p4"}, hash[:])
+
+	// the same committee refreshes its shares by resharing to itself, which is another run with other keys
+	reshare(newIDs, newIDs, 1)
+	sign(t, nodes, keyID, []string{"p3", "p4"}, hash[:])
+}
@@ -0,0 +1,100 @@
+package party
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bnb-chain/tss-lib/v2/tss"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+)
+
+// AbortError is returned when one ceremony is aborted, either by this node or by another node of the same session.
+type AbortError struct {
+	SessionID string
+
+	// party unique id reporting this failure
+	From string
+
+	// party unique ids to blame, might be empty
+	Culprits []string
+
+	Reason string
+
+	// the tss-lib error, only set if this node finds the failure itself
+	Err *tss.Error
+}
+
+func (e *AbortError) Error() string {
+	msg := fmt.Sprintf("session %s aborted by party %s: %s", e.SessionID, e.From, e.Reason)
+	if len(e.Culprits) > 0 {
+		msg += fmt.Sprintf(", culprits: [%s]", strings.Join(e.Culprits, ", "))
+	}
+	return msg
+}
+
+func (e *AbortError) Unwrap() error {
+	if e.Err == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// abort tears the given session down after its local tss party fails, and tells the other parties of this session
+// to tear it down too, within the ceremony ctx.
+func (p *party) abort(ctx context.Context, sess *session, err *tss.Error) error {
+	culprits := make([]string, 0, len(err.Culprits()))
+	for _, culprit := range err.Culprits() {
+		culprits = append(culprits, culprit.GetId())
+	}
+	// culprits are sent apart, so the reason only carries the cause
+	reason := err.Error()
+	if err.Cause() != nil {
+		reason = fmt.Sprintf("%s round %d: %v", err.Task(), err.Round(), err.Cause())
+	}
+	abortErr := &AbortError{
+		SessionID: sess.id,
+		From:      p.id.GetId(),
+		Culprits:  culprits,
+		Reason:    reason,
+		Err:       err,
+	}
+
+	// the ceremony ctx is done once the ceremony returns, so the abort is delivered before returning
+	if err := p.client.Abort(ctx, sess.id, sess.msgType, culprits, abortErr.Reason); err != nil {
+		log.Printf("error broadcasting abort of session %s: %v", sess.id, err)
+	}
+	return abortErr
+}
+
+func (p *party) OnReceiveAbort(ctx context.Context, sessionID string, msgType constants.MessageType, fromPID string, culprits []string, reason string) error {
+	// the session might not start on this node yet, the abort is queued then so the session ends right away once it
+	// starts. Nodes not joining this session just drop it once it expires.
+	return p.receive(sessionID, func(sess *session) error {
+		return p.abortSession(sess, msgType, fromPID, culprits, reason)
+	})
+}
+
+// abortSession tears down this session because of an abort from another party.
+func (p *party) abortSession(sess *session, msgType constants.MessageType, fromPID string, culprits []string, reason string) error {
+	sessionID := sess.id
+	if sess.msgType != msgType {
+		return fmt.Errorf("unexpected msg type: %s for session %s of type %s", msgType, sessionID, sess.msgType)
+	}
+
+	// only parties of this session may abort it
+	if findPartyID(sess.partyIDs, fromPID) == nil && findPartyID(sess.newPartyIDs, fromPID) == nil {
+		return fmt.Errorf("unexpected party: %s for session %s", fromPID, sessionID)
+	}
+
+	log.Printf("session %s aborted by party %s, culprits: %v, reason: %s", sessionID, fromPID, culprits, reason)
+	sess.abort(&AbortError{
+		SessionID: sessionID,
+		From:      fromPID,
+		Culprits:  culprits,
+		Reason:    reason,
+	})
+	return nil
+}
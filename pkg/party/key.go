@@ -0,0 +1,96 @@
+package party
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+
+	"github.com/bnb-chain/tss-lib/v2/common"
+	"github.com/bnb-chain/tss-lib/v2/crypto"
+	"github.com/bnb-chain/tss-lib/v2/ecdsa/keygen"
+	eddsaKeygen "github.com/bnb-chain/tss-lib/v2/eddsa/keygen"
+	"github.com/bnb-chain/tss-lib/v2/tss"
+	"github.com/decred/dcrd/dcrec/edwards/v2"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+)
+
+// KeyShare is this node's share of one shared key.
+type KeyShare struct {
+	Algorithm constants.Algorithm `json:"algorithm"`
+
+	// save data of this key share, only the one matching `Algorithm` is set
+	ECDSAData *keygen.LocalPartySaveData      `json:"ecdsa_data,omitempty"`
+	EdDSAData *eddsaKeygen.LocalPartySaveData `json:"eddsa_data,omitempty"`
+
+	// party ids holding the key shares and the threshold of the key. Party keys might differ from the roster after
+	// resharing, see `reshareKey`.
+	PartyIDs  tss.SortedPartyIDs `json:"party_ids"`
+	Threshold int                `json:"threshold"`
+}
+
+// curve returns the curve of the given algorithm.
+func curve(algorithm constants.Algorithm) (elliptic.Curve, error) {
+	switch algorithm {
+	case constants.AlgorithmECDSA:
+		return tss.S256(), nil
+	case constants.AlgorithmEdDSA:
+		return tss.Edwards(), nil
+	default:
+		return nil, fmt.Errorf("unexpected key algorithm: %s", algorithm)
+	}
+}
+
+// publicKey returns the shared public key.
+func (k *KeyShare) publicKey() *crypto.ECPoint {
+	if k.Algorithm == constants.AlgorithmEdDSA {
+		return k.EdDSAData.EDDSAPub
+	}
+	return k.ECDSAData.ECDSAPub
+}
+
+// PublicKey returns the encoded shared public key, the 33 bytes compressed point for ecdsa, or the 32 bytes ed25519
+// public key for eddsa.
+func (k *KeyShare) PublicKey() []byte {
+	pub := k.publicKey()
+	if k.Algorithm == constants.AlgorithmEdDSA {
+		return edwards.NewPublicKey(pub.X(), pub.Y()).Serialize()
+	}
+	return elliptic.MarshalCompressed(tss.S256(), pub.X(), pub.Y())
+}
+
+// VerifySignature verifies the encoded signature of the message against the shared public key, `r || s` for ecdsa,
+// or the 64 bytes ed25519 signature for eddsa.
+func (k *KeyShare) VerifySignature(msgData, signature []byte) bool {
+	if k.Algorithm == constants.AlgorithmEdDSA {
+		return len(signature) == ed25519.SignatureSize && ed25519.Verify(k.PublicKey(), msgData, signature)
+	}
+	if len(signature) != 64 {
+		return false
+	}
+	return k.verify(msgData, &common.SignatureData{R: signature[:32], S: signature[32:]})
+}
+
+// verify verifies the signature of the message against the shared public key.
+func (k *KeyShare) verify(msgData []byte, sig *common.SignatureData) bool {
+	pub := k.publicKey()
+	r, s := new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)
+	if k.Algorithm == constants.AlgorithmEdDSA {
+		pk := edwards.PublicKey{
+			Curve: tss.Edwards(),
+			X:     pub.X(),
+			Y:     pub.Y(),
+		}
+		return edwards.Verify(&pk, msgData, r, s)
+	}
+
+	pk := ecdsa.PublicKey{
+		Curve: tss.S256(),
+		X:     pub.X(),
+		Y:     pub.Y(),
+	}
+	// valid := ecdsa.VerifyASN1(&pk, new(big.Int).SetBytes(msgData).Bytes(), save.Signature)
+	return ecdsa.Verify(&pk, msgData, r, s)
+}
@@ -0,0 +1,183 @@
+package party
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrKeyNotFound is returned when the key store holds no share of the given key.
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeyStore persists this node's key shares, so they survive restarts.
+type KeyStore interface {
+	// save the key share, overwriting the existing one with the same key id
+	Save(keyID string, share *KeyShare) error
+
+	// load the key share, ErrKeyNotFound if there is no share of this key
+	Load(keyID string) (*KeyShare, error)
+
+	// delete the key share, i.e, after it has been handed over to a new committee
+	Delete(keyID string) error
+
+	// list all key ids within this store
+	List() ([]string, error)
+}
+
+// memoryKeyStore keeps key shares in memory only, which is lost once the process exits.
+type memoryKeyStore struct {
+	mu sync.RWMutex
+	m  map[string]*KeyShare
+}
+
+func NewMemoryKeyStore() KeyStore {
+	return &memoryKeyStore{
+		m: make(map[string]*KeyShare),
+	}
+}
+
+func (s *memoryKeyStore) Save(keyID string, share *KeyShare) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.m[keyID] = share
+	return nil
+}
+
+func (s *memoryKeyStore) Load(keyID string) (*KeyShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	share, ok := s.m[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return share, nil
+}
+
+func (s *memoryKeyStore) Delete(keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.m, keyID)
+	return nil
+}
+
+func (s *memoryKeyStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.m))
+	for id := range s.m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// fileKeyStore saves each key share as one json file within a directory.
+// !Important key shares are saved in plain text here. In real prod env, they should be encrypted, or saved within
+// a secure enclave/KMS.
+type fileKeyStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+const keyFileExt = ".json"
+
+func NewFileKeyStore(dir string) (KeyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating key store dir: %w", err)
+	}
+	return &fileKeyStore{dir: dir}, nil
+}
+
+func (s *fileKeyStore) path(keyID string) (string, error) {
+	if keyID == "" || keyID != filepath.Base(keyID) || strings.HasPrefix(keyID, ".") {
+		return "", fmt.Errorf("unexpected key id: %q", keyID)
+	}
+	return filepath.Join(s.dir, keyID+keyFileExt), nil
+}
+
+func (s *fileKeyStore) Save(keyID string, share *KeyShare) error {
+	path, err := s.path(keyID)
+	if err != nil {
+		return err
+	}
+	bz, err := json.Marshal(share)
+	if err != nil {
+		return fmt.Errorf("error marshaling key share: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// write to a temp file first, so a crash never leaves a half written key share behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, bz, 0600); err != nil {
+		return fmt.Errorf("error writing key share: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing key share: %w", err)
+	}
+	return nil
+}
+
+func (s *fileKeyStore) Load(keyID string) (*KeyShare, error) {
+	path, err := s.path(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bz, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading key share: %w", err)
+	}
+	share := &KeyShare{}
+	if err := json.Unmarshal(bz, share); err != nil {
+		return nil, fmt.Errorf("error unmarshaling key share: %w", err)
+	}
+	return share, nil
+}
+
+func (s *fileKeyStore) Delete(keyID string) error {
+	path, err := s.path(keyID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error deleting key share: %w", err)
+	}
+	return nil
+}
+
+func (s *fileKeyStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing key store dir: %w", err)
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), keyFileExt) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(e.Name(), keyFileExt))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,123 @@
+package party
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	p2p "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+)
+
+// nonceSize is the size of the random nonce each party contributes to one run.
+const nonceSize = 32
+
+// receivedNonce is the nonce of one party, received within the start round of one run.
+type receivedNonce struct {
+	from  string
+	nonce *p2p.Nonce
+}
+
+// agree runs the start round of one ceremony. The session id is derived from the request, the same on every run of
+// it, so each party contributes a random nonce, and all messages of this run carry the run id derived from the
+// nonces of all parties. Messages of an earlier run with the same session id never match the run id, even after
+// they are forgotten by the duplicate checks.
+//
+// Resharing old committee members announce their current key share along with their nonce, set by `announce`. The
+// nonces of all parties are returned by party unique id, and the run id covers them, so all parties of this run
+// agree on what is announced.
+func (p *party) agree(ctx context.Context, sessionID string, msgType constants.MessageType, parties []string, announce *p2p.Nonce) (string, map[string]*p2p.Nonce, error) {
+	parties = slices.Clone(parties)
+	slices.Sort(parties)
+	sess := &session{
+		id:      sessionID,
+		msgType: constants.MessageTypeNonce,
+		parties: parties,
+		nonces:  make(chan *receivedNonce, 2*len(parties)),
+	}
+	if err := p.sessions.add(sess); err != nil {
+		return "", nil, err
+	}
+	defer p.sessions.remove(sessionID)
+	go p.flush(sess)
+
+	local := &p2p.Nonce{Nonce: make([]byte, nonceSize), Key: announce.GetKey(), Threshold: announce.GetThreshold()}
+	if _, err := rand.Read(local.Nonce); err != nil {
+		return "", nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+	content, err := proto.Marshal(local)
+	if err != nil {
+		return "", nil, fmt.Errorf("error marshalling nonce: %w", err)
+	}
+	sess.send(ctx, func(ctx context.Context) error {
+		return p.client.Announce(ctx, sessionID, parties, constants.MessageTypeNonce, content)
+	})
+
+	nonces := map[string]*p2p.Nonce{p.id.GetId(): local}
+	for len(nonces) < len(parties) {
+		select {
+		case <-ctx.Done():
+			return "", nil, fmt.Errorf("%s session %s aborted waiting for the nonces of %d parties: %w", msgType, sessionID, len(parties)-len(nonces), ctx.Err())
+		case err := <-sess.undelivered:
+			return "", nil, fmt.Errorf("%s session %s failed: %w", msgType, sessionID, err)
+		case n := <-sess.nonces:
+			if nonce, ok := nonces[n.from]; ok {
+				// a party contributes once per run, so another nonce is a message of another run
+				if !proto.Equal(nonce, n.nonce) {
+					return "", nil, fmt.Errorf("%s session %s failed: party %s sends two different nonces", msgType, sessionID, n.from)
+				}
+				continue
+			}
+			nonces[n.from] = n.nonce
+		}
+	}
+	sess.drain()
+
+	var b strings.Builder
+	b.WriteString(sessionID)
+	for _, id := range parties {
+		nonce := nonces[id]
+		fmt.Fprintf(&b, "/%s:%x:%x:%d", id, nonce.GetNonce(), nonce.GetKey(), nonce.GetThreshold())
+	}
+	runID := NewSessionID(msgType, []byte(b.String()))
+	log.Printf("%s session %s runs as %s", msgType, sessionID, runID)
+	return runID, nonces, nil
+}
+
+// updateNonce hands the nonce of one party over to the start round of this session.
+func (p *party) updateNonce(sess *session, fromPID string, parties []string, content []byte) error {
+	sessionID := sess.id
+	if sess.msgType != constants.MessageTypeNonce {
+		return fmt.Errorf("unexpected msg type: %s for session %s of type %s", constants.MessageTypeNonce, sessionID, sess.msgType)
+	}
+
+	// make sure the sender runs this session with the same parties
+	parties = slices.Clone(parties)
+	slices.Sort(parties)
+	if !slices.Equal(sess.parties, parties) {
+		return fmt.Errorf("unexpected parties: %v for session %s with parties %v", parties, sessionID, sess.parties)
+	}
+	if fromPID == p.id.GetId() || !slices.Contains(sess.parties, fromPID) {
+		return fmt.Errorf("unexpected party: %s for session %s", fromPID, sessionID)
+	}
+
+	var nonce p2p.Nonce
+	if err := proto.Unmarshal(content, &nonce); err != nil {
+		return fmt.Errorf("error unmarshalling nonce: %w", err)
+	}
+	if len(nonce.GetNonce()) != nonceSize {
+		return fmt.Errorf("unexpected nonce size: %d from party %s", len(nonce.GetNonce()), fromPID)
+	}
+
+	select {
+	case sess.nonces <- &receivedNonce{from: fromPID, nonce: &nonce}:
+		return nil
+	default:
+		return fmt.Errorf("too many nonces for session %s", sessionID)
+	}
+}
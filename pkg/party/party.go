@@ -2,18 +2,21 @@ package party
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"log"
 	"math/big"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/bnb-chain/tss-lib/v2/common"
 	"github.com/bnb-chain/tss-lib/v2/ecdsa/keygen"
 	"github.com/bnb-chain/tss-lib/v2/ecdsa/signing"
+	eddsaKeygen "github.com/bnb-chain/tss-lib/v2/eddsa/keygen"
+	eddsaSigning "github.com/bnb-chain/tss-lib/v2/eddsa/signing"
 	"github.com/bnb-chain/tss-lib/v2/tss"
 
+	"github.com/smiletrl/tss-lib-starter/pkg/config"
 	"github.com/smiletrl/tss-lib-starter/pkg/constants"
 	pb "github.com/smiletrl/tss-lib-starter/pkg/grpc/client"
 )
@@ -21,212 +24,366 @@ import (
 // Step reference
 // https://docs.bnbchain.org/docs/beaconchain/learn/threshold-signature-scheme/#step-1-init-tss
 
-func NewPartyID(identifier constants.PartyIdentifier) *tss.PartyID {
+func NewPartyID(identifier config.Party) *tss.PartyID {
 	return tss.NewPartyID(identifier.ID, identifier.Moniker, new(big.Int).SetBytes([]byte(identifier.Key)))
 }
 
+// findPartyID finds the party id with the given party unique id, or nil if it's not within the list.
+func findPartyID(pIDs tss.SortedPartyIDs, id string) *tss.PartyID {
+	for _, pid := range pIDs {
+		if pid.GetId() == id {
+			return pid
+		}
+	}
+	return nil
+}
+
+// partyUniqueIDs returns the party unique ids of the list, in the same order.
+func partyUniqueIDs(pIDs tss.SortedPartyIDs) []string {
+	ids := make([]string, len(pIDs))
+	for i, pid := range pIDs {
+		ids[i] = pid.GetId()
+	}
+	return ids
+}
+
+// sameParties tells whether the party unique ids are exactly the parties of the list, in any order.
+func sameParties(pIDs tss.SortedPartyIDs, ids []string) bool {
+	if len(pIDs) != len(ids) {
+		return false
+	}
+	for _, id := range ids {
+		if findPartyID(pIDs, id) == nil {
+			return false
+		}
+	}
+	return true
+}
+
 type Party interface {
-	// set local party id
+	// set local party id, the local party of the config with its unique key
 	SetLocalID(identifier string)
 
-	// gatether all shared parties
-	GatherSharedParties()
+	// gatether all shared parties of the agreed roster, each with its unique key. Keygen and resharing wait until
+	// they are gathered, signing only needs the parties within the key share.
+	GatherSharedParties(parties []config.Party) error
 
-	// prepare keygen parameter
-	PrepareKeygen()
+	// prepare keygen parameter, so that keygen starts right away
+	PrepareKeygen() error
 
-	// run keygen process
-	Keygen() error
+	// run keygen process within the given session, to generate the key with the given key id and algorithm
+	Keygen(ctx context.Context, sessionID string, keyID string, algorithm constants.Algorithm) error
 
-	// broadcast messages to all nodes (parties)
-	MessageAll(ctx context.Context, msgType constants.MessageType, msg tss.Message)
+	// broadcast messages to all nodes (parties) of this session, and return the nodes not receiving it
+	MessageAll(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, msg tss.Message) error
 
 	// send message to one specific node
-	MessageNode(ctx context.Context, pid string, msgType constants.MessageType, msg tss.Message)
+	MessageNode(ctx context.Context, sessionID string, parties []string, pid string, msgType constants.MessageType, msg tss.Message) error
+
+	// react on one abort is received, to tear the session down
+	OnReceiveAbort(ctx context.Context, sessionID string, msgType constants.MessageType, fromPID string, culprits []string, reason string) error
 
 	// react on one message is received
-	OnReceiveMessage(ctx context.Context, msgType constants.MessageType, fromPID string, isBroadcast bool, content []byte) error
+	OnReceiveMessage(ctx context.Context, sessionID string, msgType constants.MessageType, fromPID string, parties []string, isBroadcast bool, content []byte) error
+
+	// sign the message with the given key within the given session by the given signers, any threshold+1 parties
+	// holding this key in any order, and return the verified signature
+	Sign(ctx context.Context, sessionID string, keyID string, signerIDs []string, msgData []byte) (*common.SignatureData, error)
 
-	// sign the message
-	Sign(ctx context.Context, msgData []byte) error
+	// hand the shares of the given key over from the old committee to the new committee, the public key stays the same
+	Reshare(ctx context.Context, sessionID string, keyID string, oldIDs, newIDs []string, newThreshold int) error
 
-	// hold to wait for all keygen process finishes
-	WaitForKeygen()
+	// react on one resharing message is received
+	OnReceiveReshareMessage(ctx context.Context, sessionID string, fromKey []byte, toOldCommittee, toOldAndNewCommittees, isBroadcast bool, content []byte) error
 }
 
 type party struct {
-	// local party id
-	id        *tss.PartyID
-	preParams *keygen.LocalPreParams
+	// roster and threshold of all parties
+	cfg *config.Config
 
-	keygenParty  *keygen.LocalParty
-	signingParty *signing.LocalParty
+	// local party id
+	id *tss.PartyID
 
-	// a mock key storage
-	// in real prod env, key data could be saved within a file on the device/machine or a database like Mysql, Postgres.
-	keyData *keygen.LocalPartySaveData
+	// ecdsa keygen pre-params
+	preParamsPool *PreParamsPool
 
-	// all party ids within this round
-	partyIDMap map[string]*tss.PartyID
+	// running keygen/signing/resharing ceremonies, one tss party per session
+	sessions *sessions
 
-	pIDs tss.SortedPartyIDs
+	// key storage, one key share per key id
+	keyStore KeyStore
 
-	// key finish chan
-	keyFinish chan struct{}
+	// all party ids of the agreed roster, key is party unique id
+	mu         sync.RWMutex
+	partyIDMap map[string]*tss.PartyID
+	pIDs       tss.SortedPartyIDs
 
-	// sign finish chan
-	signFinish chan struct{}
+	// closed once the roster is gathered
+	gathered     chan struct{}
+	gatheredOnce sync.Once
 
 	// p2p client
 	client pb.Client
+
+	// deadline of one keygen/signing/resharing ceremony, no deadline if 0
+	timeout time.Duration
 }
 
-func NewParty(client pb.Client) Party {
+func NewParty(cfg *config.Config, client pb.Client, keyStore KeyStore, preParamsPool *PreParamsPool, timeout time.Duration) Party {
 	return &party{
-		client:     client,
-		keyFinish:  make(chan struct{}, 1),
-		signFinish: make(chan struct{}, 1),
+		cfg:           cfg,
+		timeout:       timeout,
+		client:        client,
+		keyStore:      keyStore,
+		preParamsPool: preParamsPool,
+		sessions:      newSessions(timeout),
+		gathered:      make(chan struct{}),
 	}
 }
 
-func (p *party) GatherSharedParties() {
-	// @todo as per https://docs.bnbchain.org/docs/beaconchain/learn/threshold-signature-scheme/#step-1-init-tss, this
-	// step should probably be automatically done via message exchange among these nodes to gather all party id data in
-	// this round.
-
+func (p *party) GatherSharedParties(parties []config.Party) error {
 	// Save all shared parties in one node's local state
-	p.partyIDMap = make(map[string]*tss.PartyID)
-	parties := make([]*tss.PartyID, constants.TestParticipants)
-	for i, pi := range constants.TestPartyIdentifiers {
-		parties[i] = NewPartyID(pi)
-		p.partyIDMap[pi.ID] = parties[i]
+	partyIDMap := make(map[string]*tss.PartyID, len(parties))
+	pIDs := make([]*tss.PartyID, len(parties))
+	for i, pi := range parties {
+		if pi.Key == "" {
+			return fmt.Errorf("party %s: key is required", pi.ID)
+		}
+		pIDs[i] = NewPartyID(pi)
+		partyIDMap[pi.ID] = pIDs[i]
 	}
 
-	p.pIDs = tss.SortPartyIDs(parties)
+	p.mu.Lock()
+	p.partyIDMap = partyIDMap
+	p.pIDs = tss.SortPartyIDs(pIDs)
+	p.mu.Unlock()
+
+	p.gatheredOnce.Do(func() {
+		close(p.gathered)
+	})
+	return nil
+}
+
+// sharedParties returns the party ids of the agreed roster, waiting until they are gathered or ctx is done.
+func (p *party) sharedParties(ctx context.Context) (tss.SortedPartyIDs, map[string]*tss.PartyID, error) {
+	select {
+	case <-p.gathered:
+	default:
+		log.Printf("wait for the roster to be agreed")
+		select {
+		case <-p.gathered:
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("roster is not agreed yet: %w", ctx.Err())
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pIDs, p.partyIDMap, nil
 }
 
 func (p *party) SetLocalID(identifier string) {
-	var ok bool
-	if p.id, ok = p.partyIDMap[identifier]; !ok {
+	// the local party is known from the config, before the roster is agreed
+	local, err := p.cfg.Party(identifier)
+	if err != nil {
 		panic("unexpected identifier:" + identifier)
 	}
+	if local.Key == "" {
+		panic("key is required for the local party:" + identifier)
+	}
+	p.id = NewPartyID(local)
+
 	// set up party id for its grpc client
 	p.client.WithPartyID(p.id)
 }
 
-func (p *party) PrepareKeygen() {
-	preParams, err := keygen.GeneratePreParams(1 * time.Minute)
-	if err != nil {
-		panic("error runing keygen preparam:" + err.Error())
+func (p *party) PrepareKeygen() error {
+	if err := p.preParamsPool.Prepare(context.Background()); err != nil {
+		return fmt.Errorf("error preparing keygen pre-params: %w", err)
 	}
-	p.preParams = preParams
+	return nil
 }
 
-func (p *party) Keygen() error {
-	pIDs := p.pIDs
+func (p *party) Keygen(ctx context.Context, sessionID string, keyID string, algorithm constants.Algorithm) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	pIDs, _, err := p.sharedParties(ctx)
+	if err != nil {
+		return err
+	}
+	localID := findPartyID(pIDs, p.id.GetId())
+	if localID == nil {
+		return fmt.Errorf("party %s is not within the roster", p.id.GetId())
+	}
+
+	p2pCtx := tss.NewPeerContext(pIDs)
 
-	p2pCtx := tss.NewPeerContext(p.pIDs)
+	ec, err := curve(algorithm)
+	if err != nil {
+		return err
+	}
 
 	errCh := make(chan *tss.Error, len(pIDs))
-	outCh := make(chan tss.Message, len(pIDs))
-	endCh := make(chan *keygen.LocalPartySaveData, len(pIDs))
+	outCh := make(chan tss.Message, outBufferSize(len(pIDs)))
+	ecdsaEndCh := make(chan *keygen.LocalPartySaveData, len(pIDs))
+	eddsaEndCh := make(chan *eddsaKeygen.LocalPartySaveData, len(pIDs))
+
+	params := tss.NewParameters(ec, p2pCtx, localID, len(pIDs), p.cfg.Threshold)
+	var keygenParty tss.Party
+	if algorithm == constants.AlgorithmEdDSA {
+		keygenParty = eddsaKeygen.NewLocalParty(params, outCh, eddsaEndCh)
+	} else {
+		preParams, err := p.preParamsPool.Get(ctx)
+		if err != nil {
+			return err
+		}
+		keygenParty = keygen.NewLocalParty(params, outCh, ecdsaEndCh, *preParams)
+	}
 
-	params := tss.NewParameters(tss.S256(), p2pCtx, p.id, len(pIDs), constants.TestThreshold)
-	p.keygenParty = keygen.NewLocalParty(params, outCh, endCh).(*keygen.LocalParty)
+	// agree on the run id with all parties, once the pre-params are ready
+	parties := partyUniqueIDs(pIDs)
+	runID, _, err := p.agree(ctx, sessionID, constants.MessageTypeKeygen, parties, nil)
+	if err != nil {
+		return err
+	}
+	sess := &session{
+		id:       runID,
+		msgType:  constants.MessageTypeKeygen,
+		party:    keygenParty,
+		partyIDs: pIDs,
+	}
+	if err := p.sessions.add(sess); err != nil {
+		return err
+	}
+	defer p.sessions.remove(runID)
 
+	// queued messages are only handed over once the local party has started, tss-lib stores the messages arriving
+	// before its first round without processing them
 	go func() {
-		if err := p.keygenParty.Start(); err != nil {
+		if err := keygenParty.Start(); err != nil {
 			errCh <- err
+			return
 		}
+		p.flush(sess)
 	}()
 
 	for {
 		log.Printf("Keygen ACTIVE GOROUTINES: %d\n", runtime.NumGoroutine())
 		select {
+		case <-ctx.Done():
+			return fmt.Errorf("keygen session %s aborted: %w", sessionID, ctx.Err())
 		case err := <-errCh:
 			log.Printf("Keygen err: %v\n", err)
+			return p.abort(ctx, sess, err)
+		case err := <-sess.aborted:
+			return err
+		case err := <-sess.undelivered:
+			return fmt.Errorf("keygen session %s failed: %w", sessionID, err)
 		case msg := <-outCh:
 			log.Printf("Keygen out msg: %+v", msg)
 			dest := msg.GetTo()
 			if dest == nil {
 				// broadcast
-				go p.MessageAll(context.TODO(), constants.MessageTypeKeygen, msg)
+				sess.send(ctx, func(ctx context.Context) error {
+					return p.MessageAll(ctx, runID, parties, constants.MessageTypeKeygen, msg)
+				})
 			} else {
 				// point to point
 				if dest[0].Index == msg.GetFrom().Index {
 					return fmt.Errorf("party %d tried to send a message to itself (%d)", dest[0].Index, msg.GetFrom().Index)
 				}
-				go p.MessageNode(context.TODO(), dest[0].GetId(), constants.MessageTypeKeygen, msg)
+				sess.send(ctx, func(ctx context.Context) error {
+					return p.MessageNode(ctx, runID, parties, dest[0].GetId(), constants.MessageTypeKeygen, msg)
+				})
+			}
+		case save := <-ecdsaEndCh:
+			log.Printf("keygen save data done start")
+			if err := p.keyStore.Save(keyID, &KeyShare{
+				Algorithm: algorithm,
+				ECDSAData: save,
+				PartyIDs:  pIDs,
+				Threshold: p.cfg.Threshold,
+			}); err != nil {
+				return fmt.Errorf("error saving key share: %w", err)
 			}
-		case save := <-endCh:
+			log.Printf("keygen save data done")
+			sess.drain()
+			p.sessions.complete(runID)
+			return nil
+		case save := <-eddsaEndCh:
 			log.Printf("keygen save data done start")
-			p.keyData = save
-			p.keyFinish <- struct{}{}
+			if err := p.keyStore.Save(keyID, &KeyShare{
+				Algorithm: algorithm,
+				EdDSAData: save,
+				PartyIDs:  pIDs,
+				Threshold: p.cfg.Threshold,
+			}); err != nil {
+				return fmt.Errorf("error saving key share: %w", err)
+			}
 			log.Printf("keygen save data done")
+			sess.drain()
+			p.sessions.complete(runID)
+			return nil
 		}
 	}
+}
+
+// withTimeout applies the ceremony deadline to ctx.
+func (p *party) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.timeout)
+}
 
+// outBufferSize leaves room for the messages of one more round, so a local party still processing a late message
+// never blocks on its out chan once the ceremony loop has returned.
+func outBufferSize(parties int) int {
+	return 2 * parties
 }
 
-func (p *party) MessageAll(ctx context.Context, msgType constants.MessageType, msg tss.Message) {
+func (p *party) MessageAll(ctx context.Context, sessionID string, parties []string, msgType constants.MessageType, msg tss.Message) error {
 	// grpc request to all parties, except the trigger node
-	if err := p.client.BroadcastNodes(ctx, msgType, msg); err != nil {
+	if err := p.client.BroadcastNodes(ctx, sessionID, parties, msgType, msg); err != nil {
 		log.Printf("error broadcasting nodes: %v", err)
+		return fmt.Errorf("error broadcasting nodes: %w", err)
 	}
+	return nil
 }
 
-func (p *party) MessageNode(ctx context.Context, pid string, msgType constants.MessageType, msg tss.Message) {
+func (p *party) MessageNode(ctx context.Context, sessionID string, parties []string, pid string, msgType constants.MessageType, msg tss.Message) error {
 	// send grpc call to target node
-	if err := p.client.ToNode(ctx, pid, msgType, msg); err != nil {
+	if err := p.client.ToNode(ctx, sessionID, parties, pid, msgType, msg); err != nil {
 		log.Printf("error messaging node: %v", err)
+		return fmt.Errorf("error messaging node: %w", err)
 	}
+	return nil
 }
 
-func (p *party) verifyParty(msgType constants.MessageType) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("panic: %v", err)
+func (p *party) OnReceiveMessage(ctx context.Context, sessionID string, msgType constants.MessageType, fromPID string, parties []string, isBroadcast bool, content []byte) error {
+	return p.receive(sessionID, func(sess *session) error {
+		if msgType == constants.MessageTypeNonce {
+			return p.updateNonce(sess, fromPID, parties, content)
 		}
-	}()
+		return p.update(sess, msgType, fromPID, parties, isBroadcast, content)
+	})
+}
 
-	// find which local party to update, `keygen` or `signing`
-	var party tss.Party
-	switch msgType {
-	case constants.MessageTypeKeygen:
-		party = p.keygenParty
-	case constants.MessageTypeSigning:
-		party = p.signingParty
-	default:
-		return fmt.Errorf("unexpected msg type: %s", msgType)
-	}
+// update updates the local party of this session with one received message.
+func (p *party) update(sess *session, msgType constants.MessageType, fromPID string, parties []string, isBroadcast bool, content []byte) error {
+	sessionID := sess.id
 
-	// if party is not ready, this line will panic
-	t := party.PartyID().Id
-	if t != "" {
-		return err
+	// make sure the message matches this session's local party, `keygen` or `signing`
+	if sess.msgType != msgType {
+		return fmt.Errorf("unexpected msg type: %s for session %s of type %s", msgType, sessionID, sess.msgType)
 	}
-	return err
-}
 
-func (p *party) OnReceiveMessage(ctx context.Context, msgType constants.MessageType, fromPID string, isBroadcast bool, content []byte) error {
-	// temporary hack
-	ve := p.verifyParty(msgType)
-	for ve != nil {
-		log.Printf("Party is not ready yet: %v", ve)
-		time.Sleep(time.Second)
-		ve = p.verifyParty(msgType)
-	}
-
-	// find which local party to update, `keygen` or `signing`
-	var party tss.Party
-	switch msgType {
-	case constants.MessageTypeKeygen:
-		party = p.keygenParty
-	case constants.MessageTypeSigning:
-		party = p.signingParty
-	default:
-		return fmt.Errorf("unexpected msg type: %s", msgType)
+	// make sure the sender runs this session with the same parties, i.e, the same signers
+	if !sameParties(sess.partyIDs, parties) {
+		return fmt.Errorf("unexpected parties: %v for session %s with parties %v", parties, sessionID, partyUniqueIDs(sess.partyIDs))
 	}
+	party := sess.party
 
 	// do not send a message from this party back to itself
 	if p.id.GetId() == fromPID {
@@ -237,7 +394,10 @@ func (p *party) OnReceiveMessage(ctx context.Context, msgType constants.MessageT
 		return nil
 	}
 
-	fromParty := p.partyIDMap[fromPID]
+	fromParty := findPartyID(sess.partyIDs, fromPID)
+	if fromParty == nil {
+		return fmt.Errorf("unexpected party: %s for session %s", fromPID, sessionID)
+	}
 
 	// update local party
 	ok, err := party.UpdateFromBytes(content, fromParty, isBroadcast)
@@ -250,68 +410,134 @@ func (p *party) OnReceiveMessage(ctx context.Context, msgType constants.MessageT
 	return nil
 }
 
-func (p *party) Sign(ctx context.Context, msgData []byte) error {
-	// ideally select testThreshold+1 parties instead of all parties to sign
-	// signPIDs := p.pIDs
-	signPIDs := make(tss.SortedPartyIDs, 0, len(constants.SelectedParties))
-	for _, P := range p.pIDs {
-		if _, ok := constants.SelectedParties[P.GetId()]; ok {
-			signPIDs = append(signPIDs, P)
+// signers builds the sorted party ids of the signers, with their own indexes within this signing.
+func signers(key *KeyShare, signerIDs []string) (tss.SortedPartyIDs, error) {
+	parties := make(tss.UnSortedPartyIDs, 0, len(signerIDs))
+	for _, id := range signerIDs {
+		pid := findPartyID(key.PartyIDs, id)
+		if pid == nil {
+			return nil, fmt.Errorf("party %s holds no share of this key", id)
+		}
+		for _, P := range parties {
+			if P.GetId() == id {
+				return nil, fmt.Errorf("duplicated signer: %s", id)
+			}
 		}
+		// copy the party id, since sorting below sets its index
+		parties = append(parties, tss.NewPartyID(pid.GetId(), pid.GetMoniker(), pid.KeyInt()))
+	}
+	if len(parties) <= key.Threshold {
+		return nil, fmt.Errorf("signers %v are not enough for threshold %d, at least %d are required", signerIDs, key.Threshold, key.Threshold+1)
+	}
+	return tss.SortPartyIDs(parties), nil
+}
+
+func (p *party) Sign(ctx context.Context, sessionID string, keyID string, signerIDs []string, msgData []byte) (*common.SignatureData, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	key, err := p.keyStore.Load(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading share of key %s: %w", keyID, err)
+	}
+
+	// only the signers join this signing, tss-lib picks their data out of the key share
+	signPIDs, err := signers(key, signerIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	// PHASE: signing
 	p2pCtx := tss.NewPeerContext(signPIDs)
 
 	errCh := make(chan *tss.Error, len(signPIDs))
-	outCh := make(chan tss.Message, len(signPIDs))
+	outCh := make(chan tss.Message, outBufferSize(len(signPIDs)))
 	endCh := make(chan *common.SignatureData, len(signPIDs))
 
 	// init the party
-	params := tss.NewParameters(tss.S256(), p2pCtx, p.id, len(signPIDs), constants.TestThreshold)
-	p.signingParty = signing.NewLocalParty(new(big.Int).SetBytes(msgData), params, *p.keyData, outCh, endCh, len(msgData)).(*signing.LocalParty)
+	localID := findPartyID(signPIDs, p.id.GetId())
+	if localID == nil {
+		return nil, fmt.Errorf("party %s is not selected for signing", p.id.GetId())
+	}
+	ec, err := curve(key.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	params := tss.NewParameters(ec, p2pCtx, localID, len(signPIDs), key.Threshold)
+	var signingParty tss.Party
+	if key.Algorithm == constants.AlgorithmEdDSA {
+		signingParty = eddsaSigning.NewLocalParty(new(big.Int).SetBytes(msgData), params, *key.EdDSAData, outCh, endCh, len(msgData))
+	} else {
+		signingParty = signing.NewLocalParty(new(big.Int).SetBytes(msgData), params, *key.ECDSAData, outCh, endCh, len(msgData))
+	}
+
+	// agree on the run id with the other signers
+	parties := partyUniqueIDs(signPIDs)
+	runID, _, err := p.agree(ctx, sessionID, constants.MessageTypeSigning, parties, nil)
+	if err != nil {
+		return nil, err
+	}
+	sess := &session{
+		id:       runID,
+		msgType:  constants.MessageTypeSigning,
+		party:    signingParty,
+		partyIDs: signPIDs,
+	}
+	if err := p.sessions.add(sess); err != nil {
+		return nil, err
+	}
+	defer p.sessions.remove(runID)
 
+	// queued messages are only handed over once the local party has started, tss-lib stores the messages arriving
+	// before its first round without processing them
 	go func() {
-		if err := p.signingParty.Start(); err != nil {
+		if err := signingParty.Start(); err != nil {
 			errCh <- err
+			return
 		}
+		p.flush(sess)
 	}()
 
 	for {
 		log.Printf("Signing ACTIVE GOROUTINES: %d\n", runtime.NumGoroutine())
 		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("signing session %s aborted: %w", sessionID, ctx.Err())
 		case err := <-errCh:
 			log.Printf("Sign err: %v\n", err)
+			return nil, p.abort(ctx, sess, err)
+		case err := <-sess.aborted:
+			return nil, err
+		case err := <-sess.undelivered:
+			return nil, fmt.Errorf("signing session %s failed: %w", sessionID, err)
 		case msg := <-outCh:
 			log.Printf("Sign out msg: %+v", msg)
 			dest := msg.GetTo()
 			if dest == nil {
 				// broadcast
-				go p.MessageAll(context.TODO(), constants.MessageTypeSigning, msg)
+				sess.send(ctx, func(ctx context.Context) error {
+					return p.MessageAll(ctx, runID, parties, constants.MessageTypeSigning, msg)
+				})
 			} else {
 				if dest[0].Index == msg.GetFrom().Index {
-					return fmt.Errorf("party %d tried to send a message to itself (%d)", dest[0].Index, msg.GetFrom().Index)
+					return nil, fmt.Errorf("party %d tried to send a message to itself (%d)", dest[0].Index, msg.GetFrom().Index)
 				}
-				go p.MessageNode(context.TODO(), dest[0].GetId(), constants.MessageTypeSigning, msg)
+				sess.send(ctx, func(ctx context.Context) error {
+					return p.MessageNode(ctx, runID, parties, dest[0].GetId(), constants.MessageTypeSigning, msg)
+				})
 			}
 		case sigRaw := <-endCh:
 			log.Printf("Signature raw data: %+v", sigRaw)
 
 			// get the signature and verify it
-			pkX, pkY := p.keyData.ECDSAPub.X(), p.keyData.ECDSAPub.Y()
-			pk := ecdsa.PublicKey{
-				Curve: tss.S256(),
-				X:     pkX,
-				Y:     pkY,
-			}
-
-			// valid := ecdsa.VerifyASN1(&pk, new(big.Int).SetBytes(msgData).Bytes(), save.Signature)
-			valid := ecdsa.Verify(&pk, msgData, new(big.Int).SetBytes(sigRaw.R), new(big.Int).SetBytes(sigRaw.S))
+			valid := key.verify(msgData, sigRaw)
 			log.Printf("Signature verify result: [%+v]\n", valid)
+			if !valid {
+				return nil, fmt.Errorf("signature verification failed for key %s", keyID)
+			}
+			sess.drain()
+			p.sessions.complete(runID)
+			return sigRaw, nil
 		}
 	}
 }
-
-func (p *party) WaitForKeygen() {
-	<-p.keyFinish
-}
@@ -0,0 +1,210 @@
+package party
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnb-chain/tss-lib/v2/ecdsa/keygen"
+)
+
+const (
+	// timeout to generate one pre-params, safe primes might take minutes on a slow machine
+	preParamsTimeout = 5 * time.Minute
+
+	// wait before retrying a failed background generation
+	preParamsRetryDelay = 10 * time.Second
+
+	preParamsFileExt = ".json"
+)
+
+// PreParamsPool keeps ecdsa keygen pre-params on disk, since generating the safe primes dominates keygen time.
+//
+// With pool size 0, one pre-params is generated once, saved and reused by every keygen, including after restarts.
+// With pool size N > 0, a background goroutine keeps N fresh pre-params ready, and each of them is used only once.
+type PreParamsPool struct {
+	dir  string
+	size int
+
+	mu sync.Mutex
+	// wakes the background generator up once one pre-params is taken
+	taken chan struct{}
+}
+
+func NewPreParamsPool(dir string, size int) (*PreParamsPool, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("unexpected pre-params pool size: %d", size)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating pre-params dir: %w", err)
+	}
+	return &PreParamsPool{
+		dir:   dir,
+		size:  size,
+		taken: make(chan struct{}, 1),
+	}, nil
+}
+
+// Start runs the background generator until ctx is done. It does nothing if pool size is 0.
+func (pp *PreParamsPool) Start(ctx context.Context) {
+	if pp.size == 0 {
+		return
+	}
+	go func() {
+		for {
+			files, err := pp.files()
+			if err != nil {
+				log.Printf("error listing pre-params: %v", err)
+			}
+			if err == nil && len(files) < pp.size {
+				if _, err := pp.generate(ctx); err != nil {
+					log.Printf("error generating pre-params in background: %v", err)
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(preParamsRetryDelay):
+					}
+				}
+				continue
+			}
+
+			// pool is full, wait until one is taken
+			select {
+			case <-ctx.Done():
+				return
+			case <-pp.taken:
+			}
+		}
+	}()
+}
+
+// Prepare makes sure at least one pre-params is ready, so the next keygen starts right away.
+func (pp *PreParamsPool) Prepare(ctx context.Context) error {
+	files, err := pp.files()
+	if err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		return nil
+	}
+	_, err = pp.generate(ctx)
+	return err
+}
+
+// Get returns one ready pre-params, or generates one right away if none is ready yet.
+func (pp *PreParamsPool) Get(ctx context.Context) (*keygen.LocalPreParams, error) {
+	preParams, err := pp.take()
+	if err != nil {
+		return nil, err
+	}
+	if preParams != nil {
+		return preParams, nil
+	}
+
+	// nothing is ready, generate one now. The pool keeps it for reuse if pool size is 0.
+	if pp.size == 0 {
+		return pp.generate(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, preParamsTimeout)
+	defer cancel()
+	preParams, err = keygen.GeneratePreParamsWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error generating pre-params: %w", err)
+	}
+	return preParams, nil
+}
+
+// take loads one saved pre-params. It's removed from disk unless it's meant to be reused.
+func (pp *PreParamsPool) take() (*keygen.LocalPreParams, error) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	files, err := pp.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		bz, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading pre-params: %w", err)
+		}
+		preParams := &keygen.LocalPreParams{}
+		if err := json.Unmarshal(bz, preParams); err != nil || !preParams.ValidateWithProof() {
+			// skip broken files, i.e, written by an old version
+			log.Printf("invalid pre-params file: %s", file)
+			continue
+		}
+		if pp.size == 0 {
+			return preParams, nil
+		}
+
+		if err := os.Remove(file); err != nil {
+			return nil, fmt.Errorf("error removing used pre-params: %w", err)
+		}
+		select {
+		case pp.taken <- struct{}{}:
+		default:
+		}
+		return preParams, nil
+	}
+	return nil, nil
+}
+
+// generate generates one pre-params and saves it into the pool.
+func (pp *PreParamsPool) generate(ctx context.Context) (*keygen.LocalPreParams, error) {
+	ctx, cancel := context.WithTimeout(ctx, preParamsTimeout)
+	defer cancel()
+	preParams, err := keygen.GeneratePreParamsWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error generating pre-params: %w", err)
+	}
+	if err := pp.save(preParams); err != nil {
+		return nil, err
+	}
+	return preParams, nil
+}
+
+func (pp *PreParamsPool) save(preParams *keygen.LocalPreParams) error {
+	bz, err := json.Marshal(preParams)
+	if err != nil {
+		return fmt.Errorf("error marshaling pre-params: %w", err)
+	}
+
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	// write to a temp file first, so the pool never sees a half written file
+	name := fmt.Sprintf("%d", time.Now().UnixNano())
+	tmp := filepath.Join(pp.dir, name+".tmp")
+	if err := os.WriteFile(tmp, bz, 0600); err != nil {
+		return fmt.Errorf("error writing pre-params: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(pp.dir, name+preParamsFileExt)); err != nil {
+		return fmt.Errorf("error writing pre-params: %w", err)
+	}
+	return nil
+}
+
+// files lists all saved pre-params files, the oldest first.
+func (pp *PreParamsPool) files() ([]string, error) {
+	entries, err := os.ReadDir(pp.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing pre-params dir: %w", err)
+	}
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), preParamsFileExt) {
+			continue
+		}
+		files = append(files, filepath.Join(pp.dir, e.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
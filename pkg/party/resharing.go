@@ -0,0 +1,315 @@
+package party
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+
+	"github.com/bnb-chain/tss-lib/v2/ecdsa/keygen"
+	"github.com/bnb-chain/tss-lib/v2/ecdsa/resharing"
+	"github.com/bnb-chain/tss-lib/v2/tss"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+	p2p "github.com/smiletrl/tss-lib-starter/pkg/grpc"
+)
+
+// reshareKey derives the key of one party within the new committee. tss-lib tells which committee a party belongs to
+// by its key, so one node joining both committees needs a different key within the new committee. All nodes derive
+// the same key from the run id, which is fresh on every run.
+func reshareKey(pid *tss.PartyID, runID string) *big.Int {
+	h := sha256.New()
+	h.Write(pid.GetKey())
+	h.Write([]byte(runID))
+	return new(big.Int).SetBytes(h.Sum(nil)[:16])
+}
+
+// oldCommittee builds the sorted party ids of the old committee, and returns them along with the old threshold. Each
+// old committee member announces its own key and the threshold of its key share within the start round, so a node
+// without key share knows them too, even once the key has been reshared before. A node with key share checks the
+// announcements match its own share.
+func oldCommittee(partyIDMap map[string]*tss.PartyID, key *KeyShare, ids []string, nonces map[string]*p2p.Nonce) (tss.SortedPartyIDs, int, error) {
+	threshold := -1
+	if key != nil {
+		threshold = key.Threshold
+	}
+	parties := make(tss.UnSortedPartyIDs, 0, len(ids))
+	for _, id := range ids {
+		nonce := nonces[id]
+		if len(nonce.GetKey()) == 0 {
+			return nil, 0, fmt.Errorf("party %s announces no key of the old committee", id)
+		}
+		if threshold == -1 {
+			threshold = int(nonce.GetThreshold())
+		}
+		if int(nonce.GetThreshold()) != threshold {
+			return nil, 0, fmt.Errorf("party %s announces threshold %d, expected %d", id, nonce.GetThreshold(), threshold)
+		}
+
+		moniker := ""
+		if pid, ok := partyIDMap[id]; ok {
+			moniker = pid.GetMoniker()
+		}
+		if key != nil {
+			pid := findPartyID(key.PartyIDs, id)
+			if pid == nil {
+				return nil, 0, fmt.Errorf("party %s holds no share of this key", id)
+			}
+			if !bytes.Equal(pid.GetKey(), nonce.GetKey()) {
+				return nil, 0, fmt.Errorf("party %s announces key %x, key share has %x", id, nonce.GetKey(), pid.GetKey())
+			}
+			moniker = pid.GetMoniker()
+		}
+		parties = append(parties, tss.NewPartyID(id, moniker, new(big.Int).SetBytes(nonce.GetKey())))
+	}
+	return tss.SortPartyIDs(parties), threshold, nil
+}
+
+// newCommittee builds the sorted party ids of the new committee, with keys derived for this session.
+func newCommittee(partyIDMap map[string]*tss.PartyID, runID string, ids []string) (tss.SortedPartyIDs, error) {
+	parties := make(tss.UnSortedPartyIDs, 0, len(ids))
+	for _, id := range ids {
+		pid, ok := partyIDMap[id]
+		if !ok {
+			return nil, fmt.Errorf("unexpected party: %s", id)
+		}
+		parties = append(parties, tss.NewPartyID(pid.GetId(), pid.GetMoniker(), reshareKey(pid, runID)))
+	}
+	return tss.SortPartyIDs(parties), nil
+}
+
+// Reshare supports ecdsa keys only. A node joining the new committee only might hold no share of this key yet, so
+// it couldn't tell the key algorithm.
+func (p *party) Reshare(ctx context.Context, sessionID string, keyID string, oldIDs, newIDs []string, newThreshold int) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	// nodes joining the new committee only hold no share of this key
+	key, err := p.keyStore.Load(keyID)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return fmt.Errorf("error loading share of key %s: %w", keyID, err)
+	}
+	if key != nil && key.Algorithm != constants.AlgorithmECDSA {
+		return fmt.Errorf("unexpected key algorithm: %s for resharing, only %s is supported", key.Algorithm, constants.AlgorithmECDSA)
+	}
+
+	local := p.id.GetId()
+	inOld, inNew := slices.Contains(oldIDs, local), slices.Contains(newIDs, local)
+	if !inOld && !inNew {
+		return fmt.Errorf("party %s is within neither the old nor the new committee", local)
+	}
+	if inOld && key == nil {
+		return fmt.Errorf("party %s holds no share of key %s to reshare", local, keyID)
+	}
+	if newThreshold >= len(newIDs) {
+		return fmt.Errorf("new threshold %d is too large for %d parties", newThreshold, len(newIDs))
+	}
+
+	// parties joining the new committee are known from the roster
+	_, partyIDMap, err := p.sharedParties(ctx)
+	if err != nil {
+		return err
+	}
+	var preParams *keygen.LocalPreParams
+	if inNew {
+		if preParams, err = p.preParamsPool.Get(ctx); err != nil {
+			return err
+		}
+	}
+
+	// agree on the run id with both committees. Keys of the new committee are derived from it, so running the same
+	// resharing again, i.e, refreshing the same committee twice, never reuses the keys of the current committee.
+	parties := slices.Clone(oldIDs)
+	for _, id := range newIDs {
+		if !slices.Contains(parties, id) {
+			parties = append(parties, id)
+		}
+	}
+	// old committee members announce their key share, for the nodes without key share
+	var announce *p2p.Nonce
+	if inOld {
+		pid := findPartyID(key.PartyIDs, local)
+		if pid == nil {
+			return fmt.Errorf("party %s holds no share of key %s to reshare", local, keyID)
+		}
+		announce = &p2p.Nonce{Key: pid.GetKey(), Threshold: int32(key.Threshold)}
+	}
+	runID, nonces, err := p.agree(ctx, sessionID, constants.MessageTypeResharing, parties, announce)
+	if err != nil {
+		return err
+	}
+	oldPIDs, threshold, err := oldCommittee(partyIDMap, key, oldIDs, nonces)
+	if err != nil {
+		return fmt.Errorf("error building old committee: %w", err)
+	}
+	newPIDs, err := newCommittee(partyIDMap, runID, newIDs)
+	if err != nil {
+		return fmt.Errorf("error building new committee: %w", err)
+	}
+
+	// PHASE: resharing
+	oldCtx := tss.NewPeerContext(oldPIDs)
+	newCtx := tss.NewPeerContext(newPIDs)
+	bothCommittees := len(oldPIDs) + len(newPIDs)
+
+	errCh := make(chan *tss.Error, bothCommittees)
+	outCh := make(chan tss.Message, outBufferSize(bothCommittees))
+	endCh := make(chan *keygen.LocalPartySaveData, bothCommittees)
+
+	// init the local party within each committee this node joins
+	var oldParty, newParty tss.Party
+	if pid := findPartyID(oldPIDs, local); pid != nil {
+		params := tss.NewReSharingParameters(tss.S256(), oldCtx, newCtx, pid, len(oldPIDs), threshold, len(newPIDs), newThreshold)
+		oldParty = resharing.NewLocalParty(params, *key.ECDSAData, outCh, endCh)
+	}
+	if pid := findPartyID(newPIDs, local); pid != nil {
+		params := tss.NewReSharingParameters(tss.S256(), oldCtx, newCtx, pid, len(oldPIDs), threshold, len(newPIDs), newThreshold)
+		save := keygen.NewLocalPartySaveData(len(newPIDs))
+		save.LocalPreParams = *preParams
+		newParty = resharing.NewLocalParty(params, save, outCh, endCh)
+	}
+
+	sess := &session{
+		id:          runID,
+		msgType:     constants.MessageTypeResharing,
+		party:       oldParty,
+		partyIDs:    oldPIDs,
+		newParty:    newParty,
+		newPartyIDs: newPIDs,
+	}
+	if err := p.sessions.add(sess); err != nil {
+		return err
+	}
+	defer p.sessions.remove(runID)
+
+	// start the new party first, it will wait for messages from the old committee. Queued messages are only handed
+	// over once both local parties have started, tss-lib stores the messages arriving before its first round without
+	// processing them.
+	running := 0
+	for _, party := range []tss.Party{newParty, oldParty} {
+		if party != nil {
+			running++
+		}
+	}
+	go func() {
+		for _, party := range []tss.Party{newParty, oldParty} {
+			if party == nil {
+				continue
+			}
+			if err := party.Start(); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		p.flush(sess)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("resharing session %s aborted: %w", sessionID, ctx.Err())
+		case err := <-errCh:
+			log.Printf("resharing session %s fails: %v", sessionID, err)
+			return p.abort(ctx, sess, err)
+		case err := <-sess.aborted:
+			return err
+		case err := <-sess.undelivered:
+			return fmt.Errorf("resharing session %s failed: %w", sessionID, err)
+		case msg := <-outCh:
+			// resharing messages always come with their destinations, possibly including this node itself
+			sess.send(ctx, func(ctx context.Context) error {
+				return p.MessageAll(ctx, runID, nil, constants.MessageTypeResharing, msg)
+			})
+		case save := <-endCh:
+			// old committee members that aren't receiving a share have their Xi zeroed
+			if save.Xi != nil && save.Xi.Sign() != 0 {
+				if err := p.keyStore.Save(keyID, &KeyShare{
+					Algorithm: constants.AlgorithmECDSA,
+					ECDSAData: save,
+					PartyIDs:  newPIDs,
+					Threshold: newThreshold,
+				}); err != nil {
+					return fmt.Errorf("error saving key share: %w", err)
+				}
+				log.Printf("resharing new key share saved")
+			} else if newParty == nil {
+				if err := p.keyStore.Delete(keyID); err != nil {
+					return fmt.Errorf("error deleting key share: %w", err)
+				}
+				log.Printf("resharing old key share dropped")
+			}
+
+			running--
+			if running == 0 {
+				log.Printf("resharing process finished")
+				sess.drain()
+				p.sessions.complete(runID)
+				return nil
+			}
+		}
+	}
+}
+
+func (p *party) OnReceiveReshareMessage(ctx context.Context, sessionID string, fromKey []byte, toOldCommittee, toOldAndNewCommittees, isBroadcast bool, content []byte) error {
+	return p.receive(sessionID, func(sess *session) error {
+		return p.updateReshare(sess, fromKey, toOldCommittee, toOldAndNewCommittees, isBroadcast, content)
+	})
+}
+
+// updateReshare updates the local parties of this resharing session with one received message.
+func (p *party) updateReshare(sess *session, fromKey []byte, toOldCommittee, toOldAndNewCommittees, isBroadcast bool, content []byte) error {
+	sessionID := sess.id
+	if sess.msgType != constants.MessageTypeResharing {
+		return fmt.Errorf("unexpected msg type: %s for session %s of type %s", constants.MessageTypeResharing, sessionID, sess.msgType)
+	}
+
+	// keys are different between the two committees, so the key tells which committee the sender belongs to
+	key := new(big.Int).SetBytes(fromKey)
+	fromParty := sess.partyIDs.FindByKey(key)
+	if fromParty == nil {
+		fromParty = sess.newPartyIDs.FindByKey(key)
+	}
+	if fromParty == nil {
+		return fmt.Errorf("unexpected party key: %x for session %s", fromKey, sessionID)
+	}
+
+	// find which local party to update, within the `old` or the `new` committee
+	var parties []tss.Party
+	switch {
+	case toOldAndNewCommittees:
+		parties = []tss.Party{sess.party, sess.newParty}
+	case toOldCommittee:
+		parties = []tss.Party{sess.party}
+	default:
+		parties = []tss.Party{sess.newParty}
+	}
+
+	updated := false
+	for _, party := range parties {
+		if party == nil {
+			continue
+		}
+		updated = true
+
+		// do not send a message from this party back to itself
+		if party.PartyID().KeyInt().Cmp(key) == 0 {
+			continue
+		}
+
+		ok, err := party.UpdateFromBytes(content, fromParty, isBroadcast)
+		if err != nil {
+			return fmt.Errorf("error updating from bytes at OnReceiveReshareMessage: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("updating from bytes at OnReceiveReshareMessage fails")
+		}
+	}
+	if !updated {
+		return fmt.Errorf("party %s is not within the target committee of session %s", p.id.GetId(), sessionID)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package party
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bnb-chain/tss-lib/v2/tss"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/constants"
+)
+
+// NewSessionID derives a session id from the ceremony type and its payload, i.e, the key id, the sorted signers and
+// the message to sign. All nodes computing it from the same input get the same id without exchanging anything. Each
+// run of the session agrees on its own run id once it starts, see `agree`.
+func NewSessionID(msgType constants.MessageType, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(msgType))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// session is one running keygen, signing or resharing ceremony.
+type session struct {
+	id      string
+	msgType constants.MessageType
+	party   tss.Party
+
+	// party ids within this session, to look up message senders
+	partyIDs tss.SortedPartyIDs
+
+	// resharing only. `party` and `partyIDs` above belong to the old committee, and these two belong to the new
+	// committee. One node may join either committee or both, so `party` or `newParty` might be nil.
+	newParty    tss.Party
+	newPartyIDs tss.SortedPartyIDs
+
+	// start round only, the sorted party unique ids of this ceremony, and the nonces received from them
+	parties []string
+	nonces  chan *receivedNonce
+
+	// receives the abort from other parties of this session
+	aborted chan *AbortError
+
+	// receives the messages not delivered to other parties of this session after all retries
+	undelivered chan error
+
+	// deliveries still in flight
+	sending sync.WaitGroup
+}
+
+// abort hands the abort over to the running ceremony. Only the first abort matters.
+func (sess *session) abort(err *AbortError) {
+	select {
+	case sess.aborted <- err:
+	default:
+	}
+}
+
+// send delivers the outgoing messages in the background within the ceremony ctx, and hands the first delivery
+// failure over to the running ceremony. Peers missing a message never finish the ceremony, so there is no point
+// waiting for the timeout. Once the ceremony is over, ctx is done and the pending deliveries stop retrying.
+func (sess *session) send(ctx context.Context, deliver func(ctx context.Context) error) {
+	sess.sending.Add(1)
+	go func() {
+		defer sess.sending.Done()
+		if err := deliver(ctx); err != nil && ctx.Err() == nil {
+			select {
+			case sess.undelivered <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// drain waits for the deliveries in flight, so the last messages of a finished ceremony still reach the other
+// parties before ctx is done.
+func (sess *session) drain() {
+	sess.sending.Wait()
+}
+
+const (
+	// bounds of the messages arriving before their session starts on this node, so a peer can't make this node
+	// queue messages forever
+	maxPendingSessions = 64
+	maxPendingMessages = 256
+
+	// how long the ids of the completed sessions are kept, to drop the late messages of these sessions
+	completedSessionTTL = 10 * time.Minute
+)
+
+// ErrQueueFull is returned when a message can't be queued until its session starts, since too many messages are
+// waiting already. It's temporary, the sender should retry the message later.
+var ErrQueueFull = errors.New("too many messages waiting for their sessions to start")
+
+// errCompleted is returned for the late messages of a session completed on this node.
+var errCompleted = errors.New("session is completed")
+
+// pendingMessage is one message arriving before its session starts on this node, i.e, a faster peer has started
+// the ceremony already.
+type pendingMessage struct {
+	received time.Time
+	deliver  func(sess *session) error
+}
+
+// sessions holds all running ceremonies within this node, key is session id.
+type sessions struct {
+	mu sync.RWMutex
+	m  map[string]*session
+
+	// messages waiting for their session to start, key is session id
+	pending map[string][]*pendingMessage
+
+	// sessions completed recently, key is session id and value is completing time
+	completed map[string]time.Time
+
+	// how long queued messages are kept, no expiry if 0. Queued messages are acked to their senders already, so they
+	// are kept as long as their senders might still run the ceremony, i.e, while this node prepares its pre-params.
+	pendingTTL time.Duration
+}
+
+// newSessions keeps queued messages for the ceremony timeout, or until their sessions start if there is no timeout.
+func newSessions(pendingTTL time.Duration) *sessions {
+	return &sessions{
+		pendingTTL: pendingTTL,
+		m:          make(map[string]*session),
+		pending:    make(map[string][]*pendingMessage),
+		completed:  make(map[string]time.Time),
+	}
+}
+
+func (s *sessions) add(sess *session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.m[sess.id]; ok {
+		return fmt.Errorf("session %s is already running", sess.id)
+	}
+	sess.aborted = make(chan *AbortError, 1)
+	sess.undelivered = make(chan error, 1)
+	s.m[sess.id] = sess
+	delete(s.completed, sess.id)
+	return nil
+}
+
+func (s *sessions) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.m, id)
+}
+
+// complete marks the session completed, so its late messages are dropped instead of queued. Only successful sessions
+// are marked, a failed session might run again with the same id.
+func (s *sessions) complete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed[id] = time.Now()
+	delete(s.pending, id)
+}
+
+// getOrQueue returns the running session, or queues the message until the session starts if it's not running yet.
+func (s *sessions) getOrQueue(id string, deliver func(sess *session) error) (*session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.m[id]; ok {
+		return sess, nil
+	}
+
+	now := time.Now()
+	s.expire(now)
+	if _, ok := s.completed[id]; ok {
+		return nil, errCompleted
+	}
+	queue, ok := s.pending[id]
+	if !ok && len(s.pending) >= maxPendingSessions {
+		return nil, fmt.Errorf("%w: too many pending sessions, message of session %s", ErrQueueFull, id)
+	}
+	if len(queue) >= maxPendingMessages {
+		return nil, fmt.Errorf("%w: too many pending messages of session %s", ErrQueueFull, id)
+	}
+	s.pending[id] = append(queue, &pendingMessage{
+		received: now,
+		deliver:  deliver,
+	})
+	return nil, nil
+}
+
+// takePending removes the messages queued for this session and returns the unexpired ones.
+func (s *sessions) takePending(id string) []*pendingMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+	queue := s.pending[id]
+	delete(s.pending, id)
+	return queue
+}
+
+// expire drops the queued messages older than `pendingTTL`, and forgets the sessions completed before
+// `completedSessionTTL`. Each queue is ordered by receiving time.
+func (s *sessions) expire(now time.Time) {
+	for id, completed := range s.completed {
+		if now.Sub(completed) > completedSessionTTL {
+			delete(s.completed, id)
+		}
+	}
+	if s.pendingTTL <= 0 {
+		return
+	}
+	for id, queue := range s.pending {
+		i := 0
+		for i < len(queue) && now.Sub(queue[i].received) > s.pendingTTL {
+			i++
+		}
+		if i == 0 {
+			continue
+		}
+		log.Printf("drop %d expired messages of session %s", i, id)
+		if i == len(queue) {
+			delete(s.pending, id)
+		} else {
+			s.pending[id] = queue[i:]
+		}
+	}
+}
+
+// receive hands one message over to its running session, or queues it until the session starts on this node.
+func (p *party) receive(sessionID string, deliver func(sess *session) error) error {
+	sess, err := p.sessions.getOrQueue(sessionID, deliver)
+	if errors.Is(err, errCompleted) {
+		// the sender is slower, i.e, retrying a message after a lost ack. This node needs nothing more from it.
+		log.Printf("session %s is completed, late message dropped", sessionID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sess == nil {
+		log.Printf("Party is not ready yet for session: %s, message queued", sessionID)
+		return nil
+	}
+	return deliver(sess)
+}
+
+// flush delivers the messages arriving before this session starts.
+func (p *party) flush(sess *session) {
+	for _, msg := range p.sessions.takePending(sess.id) {
+		if err := msg.deliver(sess); err != nil {
+			log.Printf("error delivering queued message of session %s: %v", sess.id, err)
+		}
+	}
+}
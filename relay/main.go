@@ -2,9 +2,8 @@ package main
 
 import (
 	"github.com/smiletrl/tss-lib-starter/cmd"
-	"github.com/smiletrl/tss-lib-starter/pkg/constants"
 )
 
 func main() {
-	cmd.Cmd(constants.SignMessage)
+	cmd.Relay()
 }
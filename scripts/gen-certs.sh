@@ -0,0 +1,26 @@
+#!/bin/sh
+# Generates a test CA and one certificate per party within `certs` dir, for mutual tls between parties.
+# The party unique id is both the common name and the DNS name of its certificate.
+# Usage: ./scripts/gen-certs.sh [p1 p2 p3 p4], or ./scripts/gen-certs.sh relay admin for the relay and admin certificates
+set -e
+
+dir=certs
+parties=${*:-p1 p2 p3 p4}
+
+mkdir -p $dir
+cd $dir
+
+if [ ! -f ca.pem ]; then
+  openssl req -x509 -newkey ec -pkeyopt ec_paramgen_curve:P-256 -nodes -days 365 \
+    -subj "/CN=tss-lib-starter test ca" -keyout ca-key.pem -out ca.pem
+fi
+
+for p in $parties; do
+  openssl req -newkey ec -pkeyopt ec_paramgen_curve:P-256 -nodes \
+    -subj "/CN=$p" -keyout $p-key.pem -out $p.csr
+  printf "subjectAltName=DNS:%s\nextendedKeyUsage=serverAuth,clientAuth\n" $p > $p.ext
+  openssl x509 -req -in $p.csr -CA ca.pem -CAkey ca-key.pem -CAcreateserial -days 365 \
+    -extfile $p.ext -out $p.pem
+  rm $p.csr $p.ext
+done
+chmod 600 *-key.pem
@@ -0,0 +1,39 @@
+// Generates one identity per party within `identities` dir, and the registry of all their public identities.
+// Usage: go run ./scripts/gen-identities [p1 p2 p3 p4]
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/smiletrl/tss-lib-starter/pkg/identity"
+)
+
+const dir = "identities"
+
+func main() {
+	parties := os.Args[1:]
+	if len(parties) == 0 {
+		parties = []string{"p1", "p2", "p3", "p4"}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Fatalf("error creating identities dir: %v", err)
+	}
+
+	registry := identity.Registry{}
+	for _, id := range parties {
+		i, err := identity.GenerateIdentity(id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := i.Save(filepath.Join(dir, id+".json")); err != nil {
+			log.Fatal(err)
+		}
+		registry[id] = i.Entry()
+	}
+	if err := registry.Save(filepath.Join(dir, "registry.json")); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("identities of %v generated within %s", parties, dir)
+}